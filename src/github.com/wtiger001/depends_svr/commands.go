@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/wtiger001/depends_svr/db"
+)
+
+// runExport writes the whole graph or the neighborhood of one node in any of
+// the output formats, e.g. export -format mermaid -root PIR-123 -depth 2
+func runExport(args []string) {
+	var snapshot string
+	var root string
+	var depth int
+	var types string
+
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	flags.StringVar(&snapshot, "in", "output.json", "Graph to export, extracted from JIRA when the file does not exist")
+	flags.StringVar(&root, "root", "", "Key of the node to export the neighborhood of")
+	flags.IntVar(&depth, "depth", 2, "Number of links to follow from the root")
+	flags.StringVar(&types, "types", "", "Comma separated node types to keep, e.g. feature,capability")
+	cfg := getConfig(flags, args)
+
+	// Only write to a file when asked, otherwise print the diagram
+	out := ""
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "out" {
+			out = cfg.OutputFile
+		}
+	})
+
+	graph := loadGraph(snapshot, cfg)
+	var keep []string
+	for _, t := range strings.Split(types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			keep = append(keep, t)
+		}
+	}
+	err := db.Export(graph, root, depth, keep, out, cfg)
+	if err != nil {
+		fmt.Printf("Export failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runTraceMatrix writes the requirements to features and capabilities
+// traceability matrix, e.g. trace-matrix -html matrix.html -xlsx matrix.xlsx
+func runTraceMatrix(args []string) {
+	var snapshot string
+	var xlsxFile string
+
+	flags := flag.NewFlagSet("trace-matrix", flag.ExitOnError)
+	flags.StringVar(&snapshot, "in", "output.json", "Graph to report on, extracted from JIRA when the file does not exist")
+	flags.StringVar(&xlsxFile, "xlsx", "trace-matrix.xlsx", "Excel workbook file, empty to skip")
+	cfg := getConfig(flags, args)
+
+	// -html names the matrix report here rather than the graph report
+	htmlFile := "trace-matrix.html"
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "html" {
+			htmlFile = cfg.HTMLFile
+		}
+	})
+	cfg.HTMLFile = ""
+
+	graph := loadGraph(snapshot, cfg)
+	err := db.TraceMatrixReport(graph, htmlFile, xlsxFile, cfg)
+	if err != nil {
+		fmt.Printf("Trace matrix failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runCheck validates the graph for a CI job and exits with status 2 when
+// there are findings at or above the -fail-on severity, e.g.
+// check -junit check.xml -fail-on warning
+func runCheck(args []string) {
+	var snapshot string
+	var junitFile string
+	var sarifFile string
+	var failOn string
+
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	flags.StringVar(&snapshot, "in", "output.json", "Graph to check, extracted from JIRA when the file does not exist")
+	flags.StringVar(&junitFile, "junit", "check.xml", "JUnit XML results file, empty to skip")
+	flags.StringVar(&sarifFile, "sarif", "check.sarif", "SARIF JSON results file, empty to skip")
+	flags.StringVar(&failOn, "fail-on", "error", "Lowest severity that fails the check (info, warning, error or none)")
+	cfg := getConfig(flags, args)
+
+	graph := loadGraph(snapshot, cfg)
+	failed, err := db.Check(graph, junitFile, sarifFile, failOn, cfg)
+	if err != nil {
+		fmt.Printf("Check failed: %v\n", err)
+		os.Exit(1)
+	}
+	if failed > 0 {
+		fmt.Printf("%d findings at or above %s\n", failed, failOn)
+		os.Exit(2)
+	}
+}
+
+// runForecast simulates the delivery of the threads and capabilities of a
+// graph and writes the forecast report, e.g. forecast -forecast forecast.html.
+// With -out the graph is saved with the forecasts.
+func runForecast(args []string) {
+	var snapshot string
+
+	flags := flag.NewFlagSet("forecast", flag.ExitOnError)
+	flags.StringVar(&snapshot, "in", "output.json", "Graph to forecast, extracted from JIRA when the file does not exist")
+	cfg := getConfig(flags, args)
+
+	out := ""
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "out" {
+			out = cfg.OutputFile
+		}
+	})
+	if cfg.ForecastFile == "" {
+		cfg.ForecastFile = "forecast.html"
+	}
+
+	graph := loadGraph(snapshot, cfg)
+	err := db.ForecastReport(graph, cfg.ForecastFile, cfg)
+	if err == nil && out != "" {
+		err = db.Export(graph, "", 0, nil, out, cfg)
+	}
+	if err != nil {
+		fmt.Printf("Forecast failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runWhatIf tries a scenario on a copy of the graph and prints what it
+// changes, e.g. whatif -scenario replan.json -report replan-diff.json
+func runWhatIf(args []string) {
+	var snapshot string
+	var scenarioFile string
+	var reportFile string
+
+	flags := flag.NewFlagSet("whatif", flag.ExitOnError)
+	flags.StringVar(&snapshot, "in", "output.json", "Graph to try the scenario on, extracted from JIRA when the file does not exist")
+	flags.StringVar(&scenarioFile, "scenario", "scenario.json", "Scenario file with the changes to try")
+	flags.StringVar(&reportFile, "report", "", "JSON file for the differences")
+	cfg := getConfig(flags, args)
+
+	scenario, err := db.LoadScenario(scenarioFile)
+	if err != nil {
+		fmt.Printf("Unable to load %s: %v\n", scenarioFile, err)
+		os.Exit(1)
+	}
+	graph := loadGraph(snapshot, cfg)
+	result, err := db.WhatIfReport(graph, scenario, cfg)
+	if err == nil && reportFile != "" {
+		var raw []byte
+		if raw, err = json.MarshalIndent(result, "", "  "); err == nil {
+			err = ioutil.WriteFile(reportFile, raw, 0644)
+		}
+	}
+	if err != nil {
+		fmt.Printf("What if failed: %v\n", err)
+		os.Exit(1)
+	}
+	result.Print(os.Stdout)
+}
+
+// loadGraph reads the snapshot or, when there is none, extracts the graph
+// from JIRA
+func loadGraph(snapshot string, cfg *db.JiraConfig) *db.Graph {
+	if exists(snapshot) {
+		graph, err := db.LoadGraph(snapshot)
+		if err != nil {
+			fmt.Printf("Unable to load %s: %v\n", snapshot, err)
+			os.Exit(1)
+		}
+		return graph
+	}
+
+	requireCredentials(cfg)
+	return db.ExtractGraph(cfg)
+}
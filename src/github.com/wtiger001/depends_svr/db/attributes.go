@@ -0,0 +1,74 @@
+package db
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// attribute describes one of the Data fields for the exporters that need
+// typed attributes. The structural fields (id, source, target) are left out.
+type attribute struct {
+	name  string
+	kind  string
+	index int
+}
+
+var dataAttributes = buildDataAttributes()
+
+func buildDataAttributes() []attribute {
+	var attrs []attribute
+	t := reflect.TypeOf(Data{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+		if name == "id" || name == "source" || name == "target" {
+			continue
+		}
+
+		kind := "string"
+		switch f.Type.Kind() {
+		case reflect.Int, reflect.Int64:
+			kind = "int"
+		case reflect.Float32, reflect.Float64:
+			kind = "double"
+		case reflect.Bool:
+			kind = "boolean"
+		}
+		attrs = append(attrs, attribute{name: name, kind: kind, index: i})
+	}
+	return attrs
+}
+
+// value formats the attribute of the item. Zero values are reported as not
+// set, the same way they are omitted from the JSON output.
+func (attr attribute) value(d *Data) (string, bool) {
+	v := reflect.ValueOf(d).Elem().Field(attr.index)
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), v.Len() > 0
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), v.Int() != 0
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), v.Float() != 0
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), v.Bool()
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return "", false
+		}
+		if v.Type().Elem().Kind() == reflect.String {
+			return strings.Join(v.Interface().([]string), ", "), true
+		}
+	case reflect.Ptr, reflect.Map:
+		if v.IsNil() {
+			return "", false
+		}
+	}
+	raw, _ := json.Marshal(v.Interface())
+	return string(raw), true
+}
@@ -0,0 +1,132 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// velocitySprints is how many of a team's most recent closed sprints its
+// velocity is averaged over
+const velocitySprints = 3
+
+// addSprintLoad totals the issues of a sprint on the sprint node: the story
+// points committed and completed, the issues of each type and the issues and
+// points of each assignee. Every issue counts, not only the tracked types.
+func (graph *Graph) addSprintLoad(sprintID string, issues []jira.Issue, cfg *JiraConfig) {
+	n, ok := graph.m[validID(sprintID)]
+	if !ok {
+		return
+	}
+	d := n.Data
+	d.IssueTypes = make(map[string]int)
+	d.AssigneeIssues = make(map[string]int)
+	d.AssigneePoints = make(map[string]float64)
+	for _, issue := range issues {
+		if issue.Fields == nil {
+			continue
+		}
+		f := issue.Fields
+		points, _ := f.Unknowns[cfg.PointsField].(float64)
+		assignee := "Unassigned"
+		if f.Assignee != nil {
+			assignee = firstOf(f.Assignee.DisplayName, f.Assignee.Name)
+		}
+		d.IssueTypes[f.Type.Name]++
+		d.AssigneeIssues[assignee]++
+		d.Points += points
+		if points != 0 {
+			d.AssigneePoints[assignee] += points
+		}
+
+		state := &Data{}
+		if f.Status != nil {
+			state.Status = f.Status.Name
+		}
+		if f.Resolution != nil {
+			state.Resolution = f.Resolution.Name
+		}
+		if issueState(state, cfg) == "closed" {
+			d.CompletedPoints += points
+		}
+	}
+}
+
+// deriveVelocity sets each team's velocity on its sprints, from the
+// configuration or else from the completed points of its last closed sprints,
+// and warns about the sprints that are not closed yet and have more points
+// committed than that
+func (graph *Graph) deriveVelocity(cfg *JiraConfig) {
+	defer timeTrack(time.Now(), "Derive Velocity")
+
+	byTeam := make(map[string][]*Data)
+	for _, item := range graph.Items {
+		// Only the sprints loaded with their issues have a load
+		if item.Group == "nodes" && item.Data.Type == "Sprint" && item.Data.IssueTypes != nil {
+			byTeam[item.Data.Team] = append(byTeam[item.Data.Team], item.Data)
+		}
+	}
+
+	over := 0
+	for team, sprints := range byTeam {
+		velocity, configured := cfg.TeamVelocity[team]
+		if !configured {
+			var closed []*Data
+			for _, s := range sprints {
+				if s.Status == "closed" {
+					closed = append(closed, s)
+				}
+			}
+			sort.Slice(closed, func(i, j int) bool {
+				a, _ := parseDate(closed[i].FinishDate)
+				b, _ := parseDate(closed[j].FinishDate)
+				return a.After(b)
+			})
+			if len(closed) > velocitySprints {
+				closed = closed[:velocitySprints]
+			}
+			for _, s := range closed {
+				velocity += s.CompletedPoints
+			}
+			if len(closed) > 0 {
+				velocity = math.Round(10*velocity/float64(len(closed))) / 10
+			}
+		}
+
+		for _, s := range sprints {
+			s.Velocity = velocity
+			if overCommitted(s) {
+				s.Warning = fmt.Sprintf("over-committed: %g points against a velocity of %g", s.Points, velocity)
+				log.Printf("\tOver-committed sprint: %s (%s) has %g points against a velocity of %g\n", s.Label, team, s.Points, velocity)
+				over++
+			}
+		}
+	}
+	log.Printf("Found %d over-committed sprints\n", over)
+}
+
+func overCommitted(sprint *Data) bool {
+	return sprint.Status != "closed" && sprint.Velocity > 0 && sprint.Points > sprint.Velocity
+}
+
+// checkCapacity reports the sprints with more points committed than their
+// team's velocity
+func (graph *Graph) checkCapacity() (violations []*Violation) {
+	for _, item := range graph.Items {
+		d := item.Data
+		if item.Group != "nodes" || d.Type != "Sprint" || !overCommitted(d) {
+			continue
+		}
+		violations = append(violations, &Violation{
+			Rule:     "over-committed-sprint",
+			Severity: "warning",
+			Subject:  d.Id,
+			Message:  fmt.Sprintf("%s has %g points committed against a velocity of %g", d.Label, d.Points, d.Velocity),
+		})
+	}
+	return violations
+}
@@ -0,0 +1,347 @@
+package db
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// severities ranks the finding severities for the check threshold. Unknown
+// severities count as info.
+var severities = map[string]int{"info": 1, "warning": 2, "error": 3}
+
+// Check validates the graph, writes the findings as JUnit XML and SARIF
+// style JSON and counts the findings at or above the threshold severity. A
+// threshold of "none" never fails.
+func Check(graph *Graph, junitFile string, sarifFile string, threshold string, cfg *JiraConfig) (failed int, err error) {
+	defer timeTrack(time.Now(), "Check")
+
+	if _, ok := severities[threshold]; !ok && threshold != "none" {
+		return 0, fmt.Errorf("unknown severity threshold %q", threshold)
+	}
+	findings := graph.validate(cfg)
+	if junitFile != "" {
+		if err = writeFile(junitFile, func(w io.Writer) error { return writeJUnit(w, findings, threshold) }); err != nil {
+			return 0, err
+		}
+	}
+	if sarifFile != "" {
+		if err = writeFile(sarifFile, func(w io.Writer) error { return writeSARIF(w, findings) }); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, v := range findings {
+		if atLeast(v.Severity, threshold) {
+			failed++
+		}
+	}
+	log.Printf("%d of %d findings at or above %s\n", failed, len(findings), threshold)
+	return failed, nil
+}
+
+func atLeast(severity string, threshold string) bool {
+	if threshold == "none" {
+		return false
+	}
+	rank, ok := severities[severity]
+	if !ok {
+		rank = severities["info"]
+	}
+	return rank >= severities[threshold]
+}
+
+func writeFile(file string, write func(w io.Writer) error) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return write(f)
+}
+
+// findCycles reports each group of issues that depend on each other in a
+// circle, found as the strongly connected components of the issue
+// dependencies
+func (graph *Graph) findCycles(cfg *JiraConfig) (violations []*Violation) {
+	next := make(map[string][]string)
+	var ids []string
+	for _, item := range graph.Items {
+		if item.Group == "nodes" && graph.isIssue(item.Data.Id) {
+			ids = append(ids, item.Data.Id)
+		}
+		// Sprint membership edges share the dependency link name, and trace
+		// links made both ways are not a cycle
+		if item.Group != "edges" || linkKind(item.Data.Type, cfg) != "depends" || !graph.isIssue(item.Data.Source) || !graph.isIssue(item.Data.Target) {
+			continue
+		}
+		next[item.Data.Source] = appendUnique(next[item.Data.Source], item.Data.Target)
+	}
+	sort.Strings(ids)
+
+	// Tarjan's algorithm
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var connect func(id string)
+	connect = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, n := range next[id] {
+			if _, seen := index[n]; !seen {
+				connect(n)
+				if low[n] < low[id] {
+					low[id] = low[n]
+				}
+			} else if onStack[n] && index[n] < low[id] {
+				low[id] = index[n]
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+
+		var cycle []string
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			cycle = append(cycle, n)
+			if n == id {
+				break
+			}
+		}
+		if len(cycle) == 1 && !containsString(next[id], id) {
+			return
+		}
+		sort.Strings(cycle)
+		violations = append(violations, &Violation{
+			Rule:     "dependency-cycle",
+			Severity: "error",
+			Subject:  cycle[0],
+			Message:  fmt.Sprintf("%d issues depend on each other: %s", len(cycle), strings.Join(cycle, ", ")),
+		})
+	}
+	for _, id := range ids {
+		if _, seen := index[id]; !seen {
+			connect(id)
+		}
+	}
+	return violations
+}
+
+// checkSprintOrder reports the issue dependencies where the issue that is
+// depended on is planned in a sprint finishing after the first sprint of the
+// issue that depends on it
+func (graph *Graph) checkSprintOrder(cfg *JiraConfig) (violations []*Violation) {
+	_, sprintsOf := graph.sprintMembership()
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !isDependencyLink(item.Data.Type, cfg) || !graph.isIssue(item.Data.Source) || !graph.isIssue(item.Data.Target) {
+			continue
+		}
+		// Sprints finish on their finish date the same way releases do
+		provider, ok1 := graph.lastRelease(sprintsOf[item.Data.Source])
+		dependent, ok2 := graph.firstRelease(sprintsOf[item.Data.Target])
+		if ok1 && ok2 && provider.After(dependent) {
+			violations = append(violations, &Violation{
+				Rule:     "backwards-dependency",
+				Severity: "error",
+				Subject:  item.Data.Target,
+				Message: fmt.Sprintf("depends on %s which is planned to finish %s, after its own sprint finishes %s",
+					item.Data.Source, provider.Format("2006-01-02"), dependent.Format("2006-01-02")),
+			})
+		}
+	}
+	return violations
+}
+
+// checkDeadlines reports the open work of each thread, and of everything it
+// depends on, that is planned in a sprint finishing after the thread is due
+func (graph *Graph) checkDeadlines(cfg *JiraConfig) (violations []*Violation) {
+	adjacent, providers := graph.issueLinks(cfg)
+	_, sprintsOf := graph.sprintMembership()
+	for _, item := range graph.Items {
+		d := item.Data
+		due, ok := parseDate(d.FinishDate)
+		if item.Group != "nodes" || d.Type != "thread" || !ok {
+			continue
+		}
+		for _, id := range graph.closure(d, adjacent, providers) {
+			if issueState(graph.m[id].Data, cfg) != "open" {
+				continue
+			}
+			finish, ok := graph.lastRelease(sprintsOf[id])
+			if ok && finish.After(due) {
+				violations = append(violations, &Violation{
+					Rule:     "thread-deadline",
+					Severity: "error",
+					Subject:  d.Id,
+					Message: fmt.Sprintf("%s is planned to finish %s, after the thread is due %s",
+						id, finish.Format("2006-01-02"), due.Format("2006-01-02")),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes a test suite per rule with a test case per finding. The
+// findings at or above the threshold fail, the others pass with their message
+// in the output.
+func writeJUnit(w io.Writer, findings []*Violation, threshold string) error {
+	doc := junitSuites{Name: "depends"}
+	suites := make(map[string]*junitSuite)
+	var rules []string
+	for _, v := range findings {
+		s, ok := suites[v.Rule]
+		if !ok {
+			s = &junitSuite{Name: v.Rule}
+			suites[v.Rule] = s
+			rules = append(rules, v.Rule)
+		}
+		c := junitCase{Name: v.Subject, ClassName: "depends." + v.Rule}
+		text := v.Severity + ": " + v.Message
+		if atLeast(v.Severity, threshold) {
+			c.Failure = &junitFailure{Type: v.Severity, Message: v.Message, Text: text}
+			s.Failures++
+		} else {
+			c.SystemOut = text
+		}
+		s.Cases = append(s.Cases, c)
+		s.Tests++
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		s := suites[rule]
+		doc.Suites = append(doc.Suites, *s)
+		doc.Tests += s.Tests
+		doc.Failures += s.Failures
+	}
+	if len(findings) == 0 {
+		doc.Suites = append(doc.Suites, junitSuite{Name: "validation", Tests: 1, Cases: []junitCase{{Name: "graph", ClassName: "depends.validation"}}})
+		doc.Tests = 1
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name  string      `json:"name"`
+			Rules []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID  string `json:"ruleId"`
+	Level   string `json:"level"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+}
+
+// writeSARIF writes the findings in the SARIF 2.1.0 layout with the issue
+// key or item id as a logical location, since there are no source files
+func writeSARIF(w io.Writer, findings []*Violation) error {
+	run := sarifRun{Results: []sarifResult{}}
+	run.Tool.Driver.Name = "depends_svr"
+	run.Tool.Driver.Rules = []sarifRule{}
+	seen := make(map[string]bool)
+	for _, v := range findings {
+		if !seen[v.Rule] {
+			seen[v.Rule] = true
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: v.Rule})
+		}
+		r := sarifResult{RuleID: v.Rule, Level: "note"}
+		if v.Severity == "error" || v.Severity == "warning" {
+			r.Level = v.Severity
+		}
+		r.Message.Text = v.Message
+		r.Locations = []sarifLocation{{LogicalLocations: []sarifLogicalLocation{{Name: v.Subject, Kind: "issue"}}}}
+		run.Results = append(run.Results, r)
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool { return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID })
+
+	raw, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(raw)
+	return err
+}
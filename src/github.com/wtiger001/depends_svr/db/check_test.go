@@ -0,0 +1,161 @@
+package db
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestFindCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []*GraphItem
+		want  []string
+	}{
+		{
+			name: "a chain has no cycle",
+			items: []*GraphItem{
+				testNode("PIR-1", "feature", "", ""),
+				testNode("PIR-2", "feature", "", ""),
+				testEdge("PIR-1", "PIR-2", "depends on"),
+			},
+		},
+		{
+			name: "an issue that depends on itself",
+			items: []*GraphItem{
+				testNode("PIR-1", "feature", "", ""),
+				testEdge("PIR-1", "PIR-1", "depends on"),
+			},
+			want: []string{"PIR-1: 1 issues depend on each other: PIR-1"},
+		},
+		{
+			name: "three issues in a circle",
+			items: []*GraphItem{
+				testNode("PIR-1", "feature", "", ""),
+				testNode("PIR-2", "requirement", "", ""),
+				testNode("PIR-3", "thread", "", ""),
+				testNode("PIR-4", "feature", "", ""),
+				testEdge("PIR-1", "PIR-2", "depends on"),
+				testEdge("PIR-2", "PIR-3", "is a dependency of"),
+				testEdge("PIR-3", "PIR-1", "depends on"),
+				testEdge("PIR-3", "PIR-4", "depends on"),
+			},
+			want: []string{"PIR-1: 3 issues depend on each other: PIR-1, PIR-2, PIR-3"},
+		},
+		{
+			name: "sprints and other links do not close a cycle",
+			items: []*GraphItem{
+				testNode("1", "Sprint", "2017-01-02", "2017-01-16"),
+				testNode("PIR-1", "feature", "", ""),
+				testNode("PIR-2", "requirement", "", ""),
+				testSprint("1", "PIR-1"),
+				testEdge("PIR-1", "PIR-2", "depends on"),
+				testEdge("PIR-2", "PIR-1", "traces to"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, v := range testGraph(tt.items...).findCycles(testConfig()) {
+				if v.Rule != "dependency-cycle" || v.Severity != "error" {
+					t.Errorf("finding %s %s, want dependency-cycle error", v.Rule, v.Severity)
+				}
+				got = append(got, v.Subject+": "+v.Message)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findCycles() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAtLeast(t *testing.T) {
+	tests := []struct {
+		severity  string
+		threshold string
+		want      bool
+	}{
+		{"error", "error", true},
+		{"warning", "error", false},
+		{"error", "warning", true},
+		{"info", "info", true},
+		{"info", "warning", false},
+		{"unknown", "info", true},
+		{"unknown", "warning", false},
+		{"error", "none", false},
+	}
+	for _, tt := range tests {
+		if got := atLeast(tt.severity, tt.threshold); got != tt.want {
+			t.Errorf("atLeast(%q, %q) = %v, want %v", tt.severity, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+var checkFindings = []*Violation{
+	{Rule: "dependency-cycle", Severity: "error", Subject: "PIR-1", Message: "2 issues depend on each other: PIR-1, PIR-2"},
+	{Rule: "stale-dependency", Severity: "info", Subject: "PIR-3", Message: "depends on PIR-4 which is Done"},
+	{Rule: "backwards-dependency", Severity: "warning", Subject: "PIR-5", Message: "depends on <PIR-6> & more"},
+	{Rule: "dependency-cycle", Severity: "error", Subject: "PIR-7", Message: "1 issues depend on each other: PIR-7"},
+}
+
+func TestWriteJUnit(t *testing.T) {
+	tests := []struct {
+		golden    string
+		findings  []*Violation
+		threshold string
+	}{
+		{"check-warning.xml", checkFindings, "warning"},
+		{"check-none.xml", checkFindings, "none"},
+		{"check-empty.xml", nil, "error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			golden(t, tt.golden, func(w io.Writer) error { return writeJUnit(w, tt.findings, tt.threshold) })
+		})
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	tests := []struct {
+		golden   string
+		findings []*Violation
+	}{
+		{"check.sarif", checkFindings},
+		{"check-empty.sarif", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			golden(t, tt.golden, func(w io.Writer) error { return writeSARIF(w, tt.findings) })
+		})
+	}
+}
+
+// golden compares the output with testdata/<name>, or rewrites the file when
+// the tests run with -update
+func golden(t *testing.T, name string, write func(w io.Writer) error) {
+	var out bytes.Buffer
+	if err := write(&out); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(file, out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out.Bytes(), want) {
+		t.Errorf("output differs from %s:\n%s", file, out.String())
+	}
+}
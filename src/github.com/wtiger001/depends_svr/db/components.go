@@ -0,0 +1,172 @@
+package db
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ComponentMatrix is the design structure matrix (DSM) built by rolling the
+// issue level dependencies up to the components that own the issues
+type ComponentMatrix struct {
+	Components   []string               `json:"components"`
+	Matrix       [][]int                `json:"matrix"`
+	Dependencies []*ComponentDependency `json:"dependencies"`
+}
+
+// ComponentDependency is a single weighted component to component edge
+type ComponentDependency struct {
+	Source string   `json:"source"`
+	Target string   `json:"target"`
+	Count  int      `json:"count"`
+	Issues []string `json:"issues"`
+}
+
+// deriveComponentDependencies rolls the depends on and traces links between
+// issues up into component to component dependencies
+func (graph *Graph) deriveComponentDependencies(cfg *JiraConfig) *ComponentMatrix {
+	defer timeTrack(time.Now(), "Derive Component Dependencies")
+
+	owners := graph.componentsOf()
+	deps := make(map[string]*ComponentDependency)
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !isDependencyLink(item.Data.Type, cfg) {
+			continue
+		}
+		if !graph.isIssue(item.Data.Source) || !graph.isIssue(item.Data.Target) {
+			continue
+		}
+		for _, src := range owners[item.Data.Source] {
+			for _, tgt := range owners[item.Data.Target] {
+				if src == tgt {
+					continue
+				}
+				key := src + "|" + tgt
+				dep, ok := deps[key]
+				if !ok {
+					dep = &ComponentDependency{Source: src, Target: tgt}
+					deps[key] = dep
+				}
+				dep.Count++
+				dep.Issues = appendUnique(dep.Issues, item.Data.Source)
+				dep.Issues = appendUnique(dep.Issues, item.Data.Target)
+			}
+		}
+	}
+
+	// Build the matrix in a stable order
+	matrix := new(ComponentMatrix)
+	index := make(map[string]int)
+	for _, item := range graph.Items {
+		if item.Group == "nodes" && item.Data.Type == "component" {
+			matrix.Components = append(matrix.Components, item.Data.Id)
+		}
+	}
+	sort.Strings(matrix.Components)
+	for i, c := range matrix.Components {
+		index[c] = i
+		matrix.Matrix = append(matrix.Matrix, make([]int, len(matrix.Components)))
+	}
+
+	keys := make([]string, 0, len(deps))
+	for k := range deps {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		dep := deps[k]
+		sort.Strings(dep.Issues)
+		matrix.Dependencies = append(matrix.Dependencies, dep)
+		matrix.Matrix[index[dep.Source]][index[dep.Target]] = dep.Count
+	}
+
+	log.Printf("Derived %d component dependencies between %d components\n", len(matrix.Dependencies), len(matrix.Components))
+	return matrix
+}
+
+// addComponentLayer adds the weighted component dependencies as edges
+func (graph *Graph) addComponentLayer(matrix *ComponentMatrix) {
+	for _, dep := range matrix.Dependencies {
+		e := Edge()
+		e.Data.Id = dep.Source + "_COMPONENT_DEPENDS_" + dep.Target
+		e.Data.Source = dep.Source
+		e.Data.Target = dep.Target
+		e.Data.Type = "component dependency"
+		e.Data.Weight = dep.Count
+		e.Data.Issues = dep.Issues
+		e.Data.Description = strconv.Itoa(dep.Count) + " issue dependencies"
+		graph.add(e)
+	}
+}
+
+// componentsOf maps each issue to the components it has been linked to
+func (graph *Graph) componentsOf() map[string][]string {
+	owners := make(map[string][]string)
+	for _, item := range graph.Items {
+		if item.Group != "edges" {
+			continue
+		}
+		if target, ok := graph.m[item.Data.Target]; ok && target.Data.Type == "component" {
+			owners[item.Data.Source] = appendUnique(owners[item.Data.Source], item.Data.Target)
+		}
+	}
+	return owners
+}
+
+// isIssue checks that the id is a node for one of the tracked issue types
+func (graph *Graph) isIssue(id string) bool {
+	n, ok := graph.m[id]
+	return ok && n.Group == "nodes" && isIssueType(n.Data.Type)
+}
+
+func isIssueType(nodeType string) bool {
+	switch nodeType {
+	case "capability", "feature", "thread", "requirement":
+		return true
+	}
+	return false
+}
+
+// saveAs writes the matrix as JSON or, for any other extension, as CSV
+func (matrix *ComponentMatrix) saveAs(file string) (err error) {
+	defer timeTrack(time.Now(), "Save DSM as "+file)
+
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		raw, _ := json.MarshalIndent(matrix, "", "  ")
+		return ioutil.WriteFile(file, raw, 0644)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write(append([]string{""}, matrix.Components...))
+	for i, c := range matrix.Components {
+		row := []string{c}
+		for _, v := range matrix.Matrix[i] {
+			row = append(row, strconv.Itoa(v))
+		}
+		w.Write(row)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
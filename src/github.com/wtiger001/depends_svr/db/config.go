@@ -8,24 +8,55 @@ import (
 
 // Config - Configuration Object
 type JiraConfig struct {
-	Filename             string   `json:"-"`
-	User                 string   `json:"user"`
-	Password             string   `json:"password"`
-	JiraURL              string   `json:"jira_url"`
-	Projects             []string `json:"projects"`
-	CapabilityIssueType  string   `json:"capablity-issue-type"`
-	FeatureIssueType     string   `json:"feature-issue-type"`
-	RequirementIssueType string   `json:"requirement-issue-type"`
-	ThreadIssueType      string   `json:"thread-issue-type"`
-	ParentLink           string   `json:"parent-link"`
-	ChildLink            string   `json:"child-link"`
-	TracesToLink         string   `json:"traces-to-link"`
-	TracesFromLink       string   `json:"traces-from-link"`
-	DependsLinkOut       string   `json:"depends-link-out"`
-	DependsLinkIn        string   `json:"depends-link-in"`
-	ProcessPrefix        string   `json:"process-prefix"`
-	Debug                bool     `json:"debug"`
-	OutputFile           string   `json:"output-file"`
+	Filename             string             `json:"-"`
+	User                 string             `json:"user"`
+	Password             string             `json:"password"`
+	JiraURL              string             `json:"jira_url"`
+	Projects             []string           `json:"projects"`
+	CapabilityIssueType  string             `json:"capablity-issue-type"`
+	FeatureIssueType     string             `json:"feature-issue-type"`
+	RequirementIssueType string             `json:"requirement-issue-type"`
+	ThreadIssueType      string             `json:"thread-issue-type"`
+	ParentLink           string             `json:"parent-link"`
+	ChildLink            string             `json:"child-link"`
+	TracesToLink         string             `json:"traces-to-link"`
+	TracesFromLink       string             `json:"traces-from-link"`
+	DependsLinkOut       string             `json:"depends-link-out"`
+	DependsLinkIn        string             `json:"depends-link-in"`
+	ProcessPrefix        string             `json:"process-prefix"`
+	Debug                bool               `json:"debug"`
+	OutputFile           string             `json:"output-file"`
+	OutputFormat         string             `json:"output-format"`
+	DotCluster           string             `json:"dot-cluster"`
+	ComponentLayer       bool               `json:"component-layer"`
+	DSMFile              string             `json:"dsm-file"`
+	SprintDependencies   bool               `json:"sprint-dependencies"`
+	ProgramIncrements    []ProgramIncrement `json:"program-increments"`
+	ProgramBoardFile     string             `json:"program-board-file"`
+	KanbanBuckets        string             `json:"kanban-buckets"`
+	KanbanWindowDays     int                `json:"kanban-window-days"`
+	KanbanEpoch          string             `json:"kanban-epoch"`
+	Imports              []string           `json:"imports"`
+	HTMLFile             string             `json:"html-file"`
+	Layout               string             `json:"layout"`
+	SVGFile              string             `json:"svg-file"`
+	Metamodel            *Metamodel         `json:"metamodel"`
+	Policies             []string           `json:"policies"`
+	ClosedStatuses       []string           `json:"closed-statuses"`
+	CancelledStatuses    []string           `json:"cancelled-statuses"`
+	PointsField          string             `json:"points-field"`
+	TeamVelocity         map[string]float64 `json:"team-velocity"`
+	ForecastTrials       int                `json:"forecast-trials"`
+	ForecastFile         string             `json:"forecast-file"`
+}
+
+// ProgramIncrement - A group of sprints, selected by a regular expression on
+// the sprint name or else by the date range the sprint starts in
+type ProgramIncrement struct {
+	Name    string `json:"name"`
+	Start   string `json:"start"`
+	End     string `json:"end"`
+	Pattern string `json:"pattern"`
 }
 
 func (cfg *JiraConfig) Print() {
@@ -77,9 +108,24 @@ func (cfg *JiraConfig) ApplyDefaults() {
 	if cfg.JiraURL == "" {
 		cfg.JiraURL = "https://jira.di2e.net"
 	}
+	if cfg.KanbanWindowDays <= 0 {
+		cfg.KanbanWindowDays = 14
+	}
+	if cfg.KanbanEpoch == "" {
+		cfg.KanbanEpoch = "2017-01-02"
+	}
 	if cfg.OutputFile == "" {
 		cfg.OutputFile = "output.json"
 	}
+	if cfg.ForecastTrials <= 0 {
+		cfg.ForecastTrials = 10000
+	}
+	if len(cfg.ClosedStatuses) == 0 {
+		cfg.ClosedStatuses = []string{"Closed", "Resolved", "Done"}
+	}
+	if len(cfg.CancelledStatuses) == 0 {
+		cfg.CancelledStatuses = []string{"Won't Do", "Won't Fix", "Cancelled", "Rejected"}
+	}
 	cfg.Debug = false
 }
 
@@ -105,8 +151,33 @@ func (cfg *JiraConfig) Load(filename string) (err error) {
 	cfg.ChildLink = c.ChildLink
 	cfg.TracesToLink = c.TracesToLink
 	cfg.TracesFromLink = c.TracesFromLink
+	cfg.DependsLinkOut = c.DependsLinkOut
+	cfg.DependsLinkIn = c.DependsLinkIn
 	cfg.ProcessPrefix = c.ProcessPrefix
 	cfg.Debug = c.Debug
+	cfg.OutputFile = c.OutputFile
+	cfg.OutputFormat = c.OutputFormat
+	cfg.DotCluster = c.DotCluster
+	cfg.ComponentLayer = c.ComponentLayer
+	cfg.DSMFile = c.DSMFile
+	cfg.SprintDependencies = c.SprintDependencies
+	cfg.ProgramIncrements = c.ProgramIncrements
+	cfg.ProgramBoardFile = c.ProgramBoardFile
+	cfg.KanbanBuckets = c.KanbanBuckets
+	cfg.KanbanWindowDays = c.KanbanWindowDays
+	cfg.KanbanEpoch = c.KanbanEpoch
+	cfg.Imports = c.Imports
+	cfg.HTMLFile = c.HTMLFile
+	cfg.Layout = c.Layout
+	cfg.SVGFile = c.SVGFile
+	cfg.Metamodel = c.Metamodel
+	cfg.Policies = c.Policies
+	cfg.ClosedStatuses = c.ClosedStatuses
+	cfg.CancelledStatuses = c.CancelledStatuses
+	cfg.PointsField = c.PointsField
+	cfg.TeamVelocity = c.TeamVelocity
+	cfg.ForecastTrials = c.ForecastTrials
+	cfg.ForecastFile = c.ForecastFile
 
 	return nil
 }
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// writeCypher writes a Neo4j script that merges the graph into the database.
+// Nodes are matched on their id and relationships on their edge id, and the
+// properties and type label are replaced, so importing a newer extraction
+// updates the graph instead of duplicating it. A relationship whose type or
+// ends changed is deleted before it is merged again. Edges to nodes that are
+// not in the graph are left out.
+func (graph *Graph) writeCypher(w io.Writer, cfg *JiraConfig) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "CREATE CONSTRAINT depends_id IF NOT EXISTS FOR (n:Depends) REQUIRE n.id IS UNIQUE;\n\n")
+
+	// An issue whose type changed since the last import loses its old label
+	labels := make(map[string]bool)
+	for _, t := range []string{"thread", "capability", "feature", "requirement", "Sprint", "pi", "component", "version"} {
+		labels[cypherName(t, false)] = true
+	}
+	for _, item := range graph.Items {
+		if item.Group == "nodes" {
+			labels[cypherName(item.Data.Type, false)] = true
+		}
+	}
+	remove := strings.Join(sortedSet(labels), ":")
+
+	for _, item := range graph.Items {
+		if item.Group != "nodes" {
+			continue
+		}
+		fmt.Fprintf(out, "MERGE (n:Depends {id: %s}) SET n = %s REMOVE n:%s SET n:%s;\n",
+			cypherString(item.Data.Id), cypherProperties(item.Data), remove, cypherName(item.Data.Type, false))
+	}
+	fmt.Fprintf(out, "\n")
+
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !graph.exists(item.Data.Source) || !graph.exists(item.Data.Target) {
+			continue
+		}
+		id, source, target := cypherString(item.Data.Id), cypherString(item.Data.Source), cypherString(item.Data.Target)
+		relationship := cypherName(graph.canonicalLinkType(item, cfg), true)
+		fmt.Fprintf(out, "MATCH (:Depends)-[old {id: %s}]->(:Depends) WHERE type(old) <> %s OR startNode(old).id <> %s OR endNode(old).id <> %s DELETE old;\n",
+			id, cypherString(relationship), source, target)
+		fmt.Fprintf(out, "MATCH (a:Depends {id: %s}), (b:Depends {id: %s}) MERGE (a)-[r:%s {id: %s}]->(b) SET r = %s;\n",
+			source, target, relationship, id, cypherProperties(item.Data))
+	}
+
+	return out.Flush()
+}
+
+// canonicalLinkType gives both directions of a link between two issues the
+// same name. Issue edges point from the issue that is depended on, traced
+// from or is the child, so the inward link names read along the edge. Other
+// edges keep their own type.
+func (graph *Graph) canonicalLinkType(item *GraphItem, cfg *JiraConfig) string {
+	if !graph.isIssue(item.Data.Source) || !graph.isIssue(item.Data.Target) {
+		return item.Data.Type
+	}
+	switch strings.ToLower(item.Data.Type) {
+	case strings.ToLower(cfg.DependsLinkOut), strings.ToLower(cfg.DependsLinkIn):
+		return cfg.DependsLinkIn
+	case strings.ToLower(cfg.TracesToLink), strings.ToLower(cfg.TracesFromLink):
+		return cfg.TracesFromLink
+	case strings.ToLower(cfg.ParentLink), strings.ToLower(cfg.ChildLink):
+		return cfg.ChildLink
+	}
+	return item.Data.Type
+}
+
+// cypherProperties writes every Data field as a map literal, with the string
+// lists as lists
+func cypherProperties(d *Data) string {
+	props := []string{"id: " + cypherString(d.Id)}
+	for _, attr := range dataAttributes {
+		if list, ok := reflect.ValueOf(d).Elem().Field(attr.index).Interface().([]string); ok {
+			if len(list) > 0 {
+				var values []string
+				for _, v := range list {
+					values = append(values, cypherString(v))
+				}
+				props = append(props, attr.name+": ["+strings.Join(values, ", ")+"]")
+			}
+			continue
+		}
+		v, ok := attr.value(d)
+		if !ok {
+			continue
+		}
+		if attr.kind == "string" {
+			v = cypherString(v)
+		}
+		props = append(props, attr.name+": "+v)
+	}
+	if d.Source != "" {
+		props = append(props, "source: "+cypherString(d.Source), "target: "+cypherString(d.Target))
+	}
+	return "{" + strings.Join(props, ", ") + "}"
+}
+
+func cypherString(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\r", `\r`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+// cypherName turns a type into a label (Capability) or a relationship type
+// (IS_A_CHILD_OF)
+func cypherName(name string, relationship bool) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return "Unknown"
+	}
+	if relationship {
+		return strings.ToUpper(strings.Join(words, "_"))
+	}
+	for i, w := range words {
+		r, size := utf8.DecodeRuneInString(w)
+		words[i] = string(unicode.ToUpper(r)) + w[size:]
+	}
+	return strings.Join(words, "")
+}
+
+func sortedSet(set map[string]bool) []string {
+	var keys []string
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,46 @@
+package db
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteCypher(t *testing.T) {
+	feature := testNode("PIR-1", "feature", "", "")
+	feature.Data.Labels = []string{"ui", `say "hi"`}
+	graph := testGraph(feature, testNode("PIR-2", "requirement", "", ""), testEdge("PIR-1", "PIR-2", "depends on"))
+
+	var out bytes.Buffer
+	if err := graph.writeCypher(&out, testConfig()); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`labels: ["ui", "say \"hi\""]`,
+		`REMOVE n:Capability:Component:Feature:Pi:Requirement:Sprint:Thread:Version SET n:Feature;`,
+		`MATCH (:Depends)-[old {id: "PIR-1_depends_on_PIR-2"}]->(:Depends) WHERE type(old) <> "IS_A_DEPENDENCY_OF" OR startNode(old).id <> "PIR-1" OR endNode(old).id <> "PIR-2" DELETE old;`,
+		`MERGE (a)-[r:IS_A_DEPENDENCY_OF {id: "PIR-1_depends_on_PIR-2"}]->(b)`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %s in\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCypherName(t *testing.T) {
+	tests := []struct {
+		name         string
+		relationship bool
+		want         string
+	}{
+		{"is a child of", true, "IS_A_CHILD_OF"},
+		{"capability", false, "Capability"},
+		{"élément lié", false, "ÉlémentLié"},
+		{"--", false, "Unknown"},
+	}
+	for _, tt := range tests {
+		if got := cypherName(tt.name, tt.relationship); got != tt.want {
+			t.Errorf("cypherName(%q, %v) = %q, want %q", tt.name, tt.relationship, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// dotNodeStyles gives each node type its own shape and color
+var dotNodeStyles = map[string]string{
+	"capability":  `shape=box3d, style=filled, fillcolor="#f4cccc"`,
+	"feature":     `shape=box, style="rounded,filled", fillcolor="#cfe2f3"`,
+	"requirement": `shape=note, style=filled, fillcolor="#d9ead3"`,
+	"thread":      `shape=hexagon, style=filled, fillcolor="#fff2cc"`,
+	"Sprint":      `shape=cds, style=filled, fillcolor="#d9d2e9"`,
+	"component":   `shape=component, style=filled, fillcolor="#eeeeee"`,
+	"version":     `shape=folder, style=filled, fillcolor="#fce5cd"`,
+	"pi":          `shape=tab, style=filled, fillcolor="#ead1dc"`,
+}
+
+// dotEdgeStyle styles the edges by the kind of link they represent
+func dotEdgeStyle(item *GraphItem, cfg *JiraConfig) string {
+	var style string
+	switch linkKind(item.Data.Type, cfg) {
+	case "depends":
+		style = `style=solid`
+	case "traces":
+		style = `style=dashed, color="#38761d"`
+	case "parent":
+		style = `style=bold, arrowhead=diamond, color="#0b5394"`
+	case "release":
+		style = `style=dotted, color="#b45f06"`
+	default:
+		style = `style=solid, color="#666666"`
+	}
+	if item.Data.Weight > 1 {
+		style += fmt.Sprintf(", penwidth=%d", minInt(item.Data.Weight, 8))
+	}
+	if item.Data.Warning != "" {
+		style += `, color=red, fontcolor=red`
+	}
+	return style
+}
+
+// writeDOT writes the graph for Graphviz. Nodes are grouped into clusters by
+// component or sprint when configured and the sprints are chained in date
+// order so they are ranked left to right.
+func (graph *Graph) writeDOT(w io.Writer, cfg *JiraConfig) error {
+	out := bufio.NewWriter(w)
+
+	fmt.Fprintf(out, "digraph depends {\n")
+	fmt.Fprintf(out, "  rankdir=LR;\n")
+	fmt.Fprintf(out, "  node [fontname=\"Helvetica\", fontsize=10];\n")
+	fmt.Fprintf(out, "  edge [fontname=\"Helvetica\", fontsize=8];\n\n")
+
+	clusters := graph.dotClusters(cfg)
+	names := make([]string, 0, len(clusters))
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		fmt.Fprintf(out, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(out, "    label=%s;\n", dotQuote(graph.m[name].Data.Label))
+		fmt.Fprintf(out, "    style=rounded;\n")
+		for _, n := range clusters[name] {
+			fmt.Fprintf(out, "    %s;\n", dotNode(n))
+		}
+		fmt.Fprintf(out, "  }\n\n")
+	}
+	for _, n := range clusters[""] {
+		fmt.Fprintf(out, "  %s;\n", dotNode(n))
+	}
+	fmt.Fprintf(out, "\n")
+
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !graph.exists(item.Data.Source) || !graph.exists(item.Data.Target) {
+			continue
+		}
+		fmt.Fprintf(out, "  %s -> %s [label=%s, %s];\n", dotQuote(item.Data.Source), dotQuote(item.Data.Target), dotQuote(item.Data.Type), dotEdgeStyle(item, cfg))
+	}
+
+	// Rank the sprints by date with invisible edges
+	var sprints []*GraphItem
+	for _, item := range graph.Items {
+		if item.Group == "nodes" && item.Data.Type == "Sprint" {
+			sprints = append(sprints, item)
+		}
+	}
+	sort.Slice(sprints, func(i, j int) bool { return sprintBefore(sprints[i], sprints[j]) })
+	for i := 1; i < len(sprints); i++ {
+		fmt.Fprintf(out, "  %s -> %s [style=invis, weight=10];\n", dotQuote(sprints[i-1].Data.Id), dotQuote(sprints[i].Data.Id))
+	}
+
+	fmt.Fprintf(out, "}\n")
+	return out.Flush()
+}
+
+// dotClusters groups the nodes by the id of their component or sprint node.
+// Nodes that do not belong to a cluster are kept under the empty id.
+func (graph *Graph) dotClusters(cfg *JiraConfig) map[string][]*GraphItem {
+	clusters := make(map[string][]*GraphItem)
+
+	var sprintOf map[string]string
+	if cfg.DotCluster == "sprint" {
+		sprintOf = make(map[string]string)
+		_, sprintsOf := graph.sprintMembership()
+		for issue, list := range sprintsOf {
+			last := list[0]
+			for _, sprint := range list[1:] {
+				if sprintBefore(graph.m[last], graph.m[sprint]) {
+					last = sprint
+				}
+			}
+			sprintOf[issue] = last
+		}
+	}
+
+	for _, item := range graph.Items {
+		if item.Group != "nodes" {
+			continue
+		}
+		name := ""
+		switch cfg.DotCluster {
+		case "component":
+			if item.Data.Type == "component" {
+				name = item.Data.Id
+			} else if graph.exists(validID(item.Data.Component)) {
+				name = validID(item.Data.Component)
+			}
+		case "sprint":
+			if item.Data.Type == "Sprint" {
+				name = item.Data.Id
+			} else {
+				name = sprintOf[item.Data.Id]
+			}
+		}
+		clusters[name] = append(clusters[name], item)
+	}
+	return clusters
+}
+
+func dotNode(n *GraphItem) string {
+	label := n.Data.Id
+	if n.Data.Label != "" && n.Data.Label != n.Data.Id {
+		label += "\n" + n.Data.Label
+	}
+	style, ok := dotNodeStyles[n.Data.Type]
+	if !ok {
+		style = `shape=ellipse`
+	}
+	return fmt.Sprintf("%s [label=%s, %s]", dotQuote(n.Data.Id), dotQuote(label), style)
+}
+
+func dotQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\r", "", -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+	return `"` + s + `"`
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Export writes the graph, or the neighborhood of the root node when one is
+// given, in the configured format. Only the nodes of the listed types are
+// kept when there are types. The output goes to the file or to stdout when
+// there is no file and no report or image was asked for instead.
+func Export(graph *Graph, root string, depth int, types []string, file string, cfg *JiraConfig) (err error) {
+	defer timeTrack(time.Now(), "Export")
+
+	if root != "" {
+		graph, err = graph.neighborhood(root, depth)
+		if err != nil {
+			return err
+		}
+	}
+	if len(types) > 0 {
+		graph = graph.withTypes(types)
+	}
+
+	graph.layout(cfg)
+
+	if cfg.HTMLFile != "" {
+		graph.validate(cfg)
+		if err = graph.saveHTMLReport(cfg.HTMLFile, cfg); err != nil {
+			return err
+		}
+	}
+	if cfg.SVGFile != "" {
+		if err = graph.saveSVG(cfg.SVGFile, cfg); err != nil {
+			return err
+		}
+	}
+
+	if file == "" && cfg.OutputFormat == "" && (cfg.HTMLFile != "" || cfg.SVGFile != "") {
+		return nil
+	}
+	if file != "" {
+		return graph.writeFile(file, outputFormat(file, cfg.OutputFormat), cfg)
+	}
+	return graph.write(os.Stdout, outputFormat(file, cfg.OutputFormat), cfg)
+}
+
+// neighborhood copies the nodes within depth links of the root, following the
+// links in both directions, along with the edges between them
+func (graph *Graph) neighborhood(root string, depth int) (*Graph, error) {
+	root = validID(root)
+	if !graph.exists(root) {
+		return nil, fmt.Errorf("node %s is not in the graph", root)
+	}
+
+	adjacent := make(map[string][]string)
+	for _, item := range graph.Items {
+		if item.Group == "edges" && graph.exists(item.Data.Source) && graph.exists(item.Data.Target) {
+			adjacent[item.Data.Source] = append(adjacent[item.Data.Source], item.Data.Target)
+			adjacent[item.Data.Target] = append(adjacent[item.Data.Target], item.Data.Source)
+		}
+	}
+
+	seen := map[string]bool{root: true}
+	frontier := []string{root}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []string
+		for _, id := range frontier {
+			for _, n := range adjacent[id] {
+				if !seen[n] {
+					seen[n] = true
+					next = append(next, n)
+				}
+			}
+		}
+		sort.Strings(next)
+		frontier = next
+	}
+
+	sub := NewGraph()
+	for _, item := range graph.Items {
+		if item.Group == "nodes" && seen[item.Data.Id] {
+			sub.add(item)
+		}
+	}
+	for _, item := range graph.Items {
+		if item.Group == "edges" && seen[item.Data.Source] && seen[item.Data.Target] {
+			sub.add(item)
+		}
+	}
+
+	log.Printf("Neighborhood of %s to depth %d contains %d nodes and edges\n", root, depth, len(sub.Items))
+	return sub, nil
+}
+
+// withTypes copies the nodes of the given types and the edges between them
+func (graph *Graph) withTypes(types []string) *Graph {
+	keep := make(map[string]bool)
+	for _, t := range types {
+		keep[strings.ToLower(t)] = true
+	}
+
+	sub := NewGraph()
+	for _, item := range graph.Items {
+		if item.Group == "nodes" && keep[strings.ToLower(item.Data.Type)] {
+			sub.add(item)
+		}
+	}
+	for _, item := range graph.Items {
+		if item.Group == "edges" && sub.exists(item.Data.Source) && sub.exists(item.Data.Target) {
+			sub.add(item)
+		}
+	}
+	return sub
+}
+
+// diagramIDs gives each node a short alias since the diagram languages are
+// picky about the characters in identifiers
+func (graph *Graph) diagramIDs() map[string]string {
+	ids := make(map[string]string)
+	for _, item := range graph.Items {
+		if item.Group == "nodes" {
+			ids[item.Data.Id] = fmt.Sprintf("n%d", len(ids))
+		}
+	}
+	return ids
+}
+
+func diagramLabel(n *GraphItem) string {
+	if n.Data.Label == "" || n.Data.Label == n.Data.Id {
+		return n.Data.Id
+	}
+	return n.Data.Id + ": " + n.Data.Label
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,348 @@
+package db
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"time"
+)
+
+// forecastHorizon is how many throughput windows a trial may run before the
+// work is reported as not finishing
+const forecastHorizon = 260
+
+// Forecast is the simulated completion of the open work of a thread or
+// capability. P50, P85 and P95 are the dates by which that share of the
+// trials finished; they are blank when the trials run past the horizon.
+// OnTime is the percentage of trials that finished by the due date.
+type Forecast struct {
+	Remaining float64 `json:"remaining"`
+	Unit      string  `json:"unit"`
+	P50       string  `json:"p50,omitempty"`
+	P85       string  `json:"p85,omitempty"`
+	P95       string  `json:"p95,omitempty"`
+	Due       string  `json:"due,omitempty"`
+	OnTime    float64 `json:"on_time"`
+}
+
+// Late checks whether the 85% date is after the due date
+func (f *Forecast) Late() bool {
+	if f.Due == "" {
+		return false
+	}
+	return f.P85 == "" || f.P85 > f.Due
+}
+
+// ForecastRun describes the throughput a forecast was simulated with
+type ForecastRun struct {
+	Start      string
+	Unit       string
+	WindowDays int
+	Samples    []float64
+	Trials     int
+	Items      []*Data
+}
+
+// ForecastReport simulates the delivery of the threads and capabilities and
+// writes the forecast report
+func ForecastReport(graph *Graph, htmlFile string, cfg *JiraConfig) error {
+	graph.forecast(cfg)
+	if htmlFile == "" {
+		return nil
+	}
+	return graph.saveForecast(htmlFile, cfg)
+}
+
+// saveForecast writes the report of the last forecast, running one if needed
+func (graph *Graph) saveForecast(file string, cfg *JiraConfig) error {
+	run := graph.forecastRun
+	if run == nil {
+		if run = graph.forecast(cfg); run == nil {
+			return fmt.Errorf("no throughput from closed sprints to forecast with")
+		}
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return forecastTemplate.Execute(f, run)
+}
+
+// forecast runs a Monte Carlo simulation for each thread and capability.
+// Each trial draws past throughput windows at random until the open work of
+// the item and everything it depends on is used up. The items are simulated
+// on their own, as if they had the whole throughput to themselves. The random
+// source is seeded so the same graph gives the same forecast.
+func (graph *Graph) forecast(cfg *JiraConfig) *ForecastRun {
+	defer timeTrack(time.Now(), "Forecast")
+
+	run := graph.throughput(cfg)
+	if run == nil {
+		log.Printf("No throughput from closed sprints to forecast with\n")
+		return nil
+	}
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	run.Start = start.Format("2006-01-02")
+
+	// Issues without an estimate count as the mean estimate
+	var estimated float64
+	count := 0
+	for _, item := range graph.Items {
+		if item.Group == "nodes" && item.Data.Points > 0 && graph.isIssue(item.Data.Id) {
+			estimated += item.Data.Points
+			count++
+		}
+	}
+	mean := 1.0
+	if count > 0 {
+		mean = estimated / float64(count)
+	}
+
+	adjacent, providers := graph.issueLinks(cfg)
+	for _, item := range graph.Items {
+		d := item.Data
+		if item.Group != "nodes" || (d.Type != "capability" && d.Type != "thread") {
+			continue
+		}
+		f := &Forecast{Unit: run.Unit, Remaining: graph.remainingWork(d, run.Unit, mean, adjacent, providers, cfg)}
+		due, hasDue := parseDate(d.FinishDate)
+		if hasDue {
+			f.Due = due.Format("2006-01-02")
+		}
+
+		rng := rand.New(rand.NewSource(1))
+		windows := make([]int, run.Trials)
+		onTime := 0
+		for t := range windows {
+			left, n := f.Remaining, 0
+			for left > 0 && n <= forecastHorizon {
+				left -= run.Samples[rng.Intn(len(run.Samples))]
+				n++
+			}
+			windows[t] = n
+			if hasDue && n <= forecastHorizon && !start.AddDate(0, 0, n*run.WindowDays).After(due) {
+				onTime++
+			}
+		}
+		sort.Ints(windows)
+		date := func(p float64) string {
+			n := windows[int(math.Ceil(p*float64(len(windows))))-1]
+			if n > forecastHorizon {
+				return ""
+			}
+			return start.AddDate(0, 0, n*run.WindowDays).Format("2006-01-02")
+		}
+		f.P50, f.P85, f.P95 = date(0.5), date(0.85), date(0.95)
+		if hasDue {
+			f.OnTime = percent(onTime, run.Trials)
+		}
+		d.Forecast = f
+		run.Items = append(run.Items, d)
+
+		if d.Type == "thread" {
+			log.Printf("\t%s %g %s left: P50 %s P85 %s P95 %s due %s (%g%% on time)\n", d.Id, f.Remaining, f.Unit, f.P50, f.P85, f.P95, f.Due, f.OnTime)
+		}
+	}
+
+	sort.Slice(run.Items, func(i, j int) bool {
+		if run.Items[i].Type != run.Items[j].Type {
+			return run.Items[i].Type == "thread"
+		}
+		return run.Items[i].Id < run.Items[j].Id
+	})
+	graph.forecastRun = run
+	return run
+}
+
+// throughput collects what the closed sprints finished, in story points when
+// they are mapped and otherwise in issues, summed over windows of the usual
+// sprint length so that teams working side by side add up. Only the features
+// and requirements count, the same work the remaining work is made of, and
+// each once, in the last closed sprint it was planned in.
+func (graph *Graph) throughput(cfg *JiraConfig) *ForecastRun {
+	run := &ForecastRun{Unit: "issues", Trials: cfg.ForecastTrials}
+	_, sprintsOf := graph.sprintMembership()
+
+	type sprint struct {
+		finish time.Time
+		points float64
+		issues float64
+	}
+	closed := make(map[string]*sprint)
+	var lengths []int
+	for _, item := range graph.Items {
+		d := item.Data
+		if item.Group != "nodes" || d.Type != "Sprint" || d.Status != "closed" {
+			continue
+		}
+		start, ok1 := parseDate(d.StartDate)
+		finish, ok2 := parseDate(d.FinishDate)
+		if !ok1 || !ok2 {
+			continue
+		}
+		closed[d.Id] = &sprint{finish: finish}
+		lengths = append(lengths, int(math.Round(finish.Sub(start).Hours()/24)))
+	}
+	if len(closed) == 0 {
+		return nil
+	}
+
+	points := false
+	for _, item := range graph.Items {
+		d := item.Data
+		if item.Group != "nodes" || (d.Type != "feature" && d.Type != "requirement") || issueState(d, cfg) != "closed" {
+			continue
+		}
+		var last *sprint
+		for _, id := range sprintsOf[d.Id] {
+			if s, ok := closed[id]; ok && (last == nil || s.finish.After(last.finish)) {
+				last = s
+			}
+		}
+		if last == nil {
+			continue
+		}
+		last.issues++
+		last.points += d.Points
+		points = points || (cfg.PointsField != "" && d.Points > 0)
+	}
+	var sprints []*sprint
+	for _, s := range closed {
+		sprints = append(sprints, s)
+	}
+	if points {
+		run.Unit = "points"
+	}
+
+	sort.Ints(lengths)
+	run.WindowDays = lengths[len(lengths)/2]
+	if run.WindowDays < 1 {
+		run.WindowDays = 14
+	}
+	sort.Slice(sprints, func(i, j int) bool { return sprints[i].finish.Before(sprints[j].finish) })
+	first := sprints[0].finish
+	for _, s := range sprints {
+		w := int(s.finish.Sub(first).Hours() / 24 / float64(run.WindowDays))
+		for len(run.Samples) <= w {
+			run.Samples = append(run.Samples, 0)
+		}
+		if points {
+			run.Samples[w] += s.points
+		} else {
+			run.Samples[w] += s.issues
+		}
+	}
+
+	total := 0.0
+	for _, s := range run.Samples {
+		total += s
+	}
+	if total == 0 {
+		return nil
+	}
+	return run
+}
+
+// remainingWork totals the open features and requirements of the item and of
+// everything it depends on
+func (graph *Graph) remainingWork(d *Data, unit string, mean float64, adjacent map[string][]string, providers map[string][]string, cfg *JiraConfig) float64 {
+	total := 0.0
+	for _, id := range graph.closure(d, adjacent, providers) {
+		n := graph.m[id].Data
+		if issueState(n, cfg) != "open" {
+			continue
+		}
+		switch {
+		case unit != "points":
+			total++
+		case n.Points > 0:
+			total += n.Points
+		default:
+			total += mean
+		}
+	}
+	return math.Round(10*total) / 10
+}
+
+// closure lists the features and requirements of the item and of everything
+// it depends on, following the dependencies all the way down
+func (graph *Graph) closure(d *Data, adjacent map[string][]string, providers map[string][]string) []string {
+	seen := make(map[string]bool)
+	var work []string
+	queue := append(graph.workOf(d, adjacent, providers), providers[d.Id]...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		n := graph.m[id].Data
+		queue = append(queue, providers[id]...)
+		if n.Type == "capability" || n.Type == "thread" {
+			queue = append(queue, graph.workOf(n, adjacent, providers)...)
+			continue
+		}
+		work = append(work, id)
+	}
+	sort.Strings(work)
+	return work
+}
+
+// checkForecasts reports the items that are unlikely to finish by their
+// finish date
+func (graph *Graph) checkForecasts() (violations []*Violation) {
+	for _, item := range graph.Items {
+		f := item.Data.Forecast
+		if item.Group != "nodes" || f == nil || !f.Late() {
+			continue
+		}
+		p85 := f.P85
+		if p85 == "" {
+			p85 = "never"
+		}
+		violations = append(violations, &Violation{
+			Rule:     "forecast-late",
+			Severity: "warning",
+			Subject:  item.Data.Id,
+			Message:  fmt.Sprintf("85%% likely done %s, due %s (%g%% chance on time)", p85, f.Due, f.OnTime),
+		})
+	}
+	return violations
+}
+
+var forecastTemplate = template.Must(template.New("forecast").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Delivery Forecast</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #999; padding: 4px; }
+th { background: #eee; }
+td.number { text-align: right; }
+.late { background: #f4cccc; }
+</style>
+</head>
+<body>
+<h1>Delivery Forecast</h1>
+<p>{{.Trials}} trials from {{.Start}} drawing from {{len .Samples}} windows of {{.WindowDays}} days of closed sprint throughput in {{.Unit}}.
+Each item is simulated on its own with the open work of everything it depends on.</p>
+<table>
+<tr><th>Key</th><th>Type</th><th>Summary</th><th>Remaining</th><th>P50</th><th>P85</th><th>P95</th><th>Due</th><th>On Time</th></tr>
+{{range .Items}}{{with .Forecast}}<tr{{if .Late}} class="late"{{end}}>{{end}}
+<td>{{.Id}}</td><td>{{.Type}}</td><td>{{.Label}}</td>{{with .Forecast}}
+<td class="number">{{.Remaining}} {{.Unit}}</td><td>{{or .P50 "never"}}</td><td>{{or .P85 "never"}}</td><td>{{or .P95 "never"}}</td>
+<td>{{.Due}}</td><td class="number">{{if .Due}}{{.OnTime}}%{{end}}</td>{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// writeGantt writes the schedule as a Mermaid Gantt chart. A Mermaid task
+// can only follow another by giving up its own dates, so the planned dates are
+// kept and the predecessors are named in the task instead.
+func (graph *Graph) writeGantt(w io.Writer, cfg *JiraConfig) error {
+	out := bufio.NewWriter(w)
+	ids := graph.diagramIDs()
+
+	fmt.Fprintf(out, "gantt\n")
+	fmt.Fprintf(out, "  title Depends Schedule\n")
+	fmt.Fprintf(out, "  dateFormat YYYY-MM-DD\n")
+	section := ""
+	for _, t := range graph.schedule(cfg) {
+		if t.section != section {
+			section = t.section
+			fmt.Fprintf(out, "  section %s\n", section)
+		}
+		name := t.name
+		if len(t.predecessors) > 0 {
+			name += " (after " + strings.Join(t.predecessors, ", ") + ")"
+		}
+		// Colons and hashes end the task name
+		name = strings.NewReplacer(":", " ", "#", " ", ";", " ").Replace(name)
+		if t.milestone {
+			fmt.Fprintf(out, "  %s :milestone, %s, %s, 0d\n", name, ids[t.id], t.start.Format("2006-01-02"))
+			continue
+		}
+		fmt.Fprintf(out, "  %s :%s, %s, %s\n", name, ids[t.id], t.start.Format("2006-01-02"), t.finish.Format("2006-01-02"))
+	}
+
+	return out.Flush()
+}
+
+type msProject struct {
+	XMLName           xml.Name        `xml:"Project"`
+	Xmlns             string          `xml:"xmlns,attr"`
+	Name              string          `xml:"Name"`
+	Title             string          `xml:"Title"`
+	CreationDate      string          `xml:"CreationDate"`
+	ScheduleFromStart int             `xml:"ScheduleFromStart"`
+	StartDate         string          `xml:"StartDate"`
+	Tasks             []msProjectTask `xml:"Tasks>Task"`
+}
+
+type msProjectTask struct {
+	UID            int                    `xml:"UID"`
+	ID             int                    `xml:"ID"`
+	Name           string                 `xml:"Name"`
+	OutlineLevel   int                    `xml:"OutlineLevel"`
+	Start          string                 `xml:"Start"`
+	Finish         string                 `xml:"Finish"`
+	Duration       string                 `xml:"Duration"`
+	Milestone      int                    `xml:"Milestone"`
+	Summary        int                    `xml:"Summary"`
+	ConstraintType int                    `xml:"ConstraintType"`
+	ConstraintDate string                 `xml:"ConstraintDate,omitempty"`
+	Notes          string                 `xml:"Notes,omitempty"`
+	Predecessors   []msProjectPredecessor `xml:"PredecessorLink"`
+}
+
+type msProjectPredecessor struct {
+	PredecessorUID int `xml:"PredecessorUID"`
+	Type           int `xml:"Type"`
+}
+
+const msProjectTime = "2006-01-02T15:04:05"
+
+// writeMSProject writes the schedule as Microsoft Project XML. Each section
+// is a summary task, the tasks start no earlier than their planned dates and
+// the dependencies become finish to start predecessor links.
+func (graph *Graph) writeMSProject(w io.Writer, cfg *JiraConfig) error {
+	tasks := graph.schedule(cfg)
+	doc := msProject{
+		Xmlns:             "http://schemas.microsoft.com/project",
+		Name:              "Depends Schedule",
+		Title:             "Depends Schedule",
+		CreationDate:      time.Now().Format(msProjectTime),
+		ScheduleFromStart: 1,
+	}
+
+	uids := make(map[string]int)
+	for i, t := range tasks {
+		uids[t.id] = i + 1
+	}
+	next := len(tasks) + 1
+
+	var earliest time.Time
+	section := ""
+	summary := -1
+	var summaryStart, summaryFinish time.Time
+	closeSummary := func() {
+		if summary >= 0 {
+			doc.Tasks[summary].Start = workStart(summaryStart)
+			doc.Tasks[summary].Finish = workFinish(summaryFinish)
+			doc.Tasks[summary].Duration = msProjectDuration(summaryStart, summaryFinish)
+		}
+	}
+	for _, t := range tasks {
+		if t.section != section {
+			closeSummary()
+			section = t.section
+			doc.Tasks = append(doc.Tasks, msProjectTask{UID: next, Name: section, OutlineLevel: 1, Summary: 1})
+			summary = len(doc.Tasks) - 1
+			summaryStart, summaryFinish = t.start, t.finish
+			next++
+		}
+		if t.start.Before(summaryStart) {
+			summaryStart = t.start
+		}
+		if t.finish.After(summaryFinish) {
+			summaryFinish = t.finish
+		}
+		if earliest.IsZero() || t.start.Before(earliest) {
+			earliest = t.start
+		}
+
+		task := msProjectTask{
+			UID:            uids[t.id],
+			Name:           t.name,
+			OutlineLevel:   2,
+			Start:          workStart(t.start),
+			Finish:         workFinish(t.finish),
+			Duration:       msProjectDuration(t.start, t.finish),
+			ConstraintType: 4,
+			ConstraintDate: workStart(t.start),
+		}
+		if t.milestone {
+			task.Milestone = 1
+			task.Finish = task.Start
+			task.Duration = "PT0H0M0S"
+		}
+		if n, ok := graph.m[t.node]; ok {
+			task.Notes = n.Data.Description
+		}
+		for _, p := range t.predecessors {
+			task.Predecessors = append(task.Predecessors, msProjectPredecessor{PredecessorUID: uids[p], Type: 1})
+		}
+		doc.Tasks = append(doc.Tasks, task)
+	}
+	closeSummary()
+	for i := range doc.Tasks {
+		doc.Tasks[i].ID = i + 1
+	}
+	if earliest.IsZero() {
+		earliest = time.Now()
+	}
+	doc.StartDate = workStart(earliest)
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+func workStart(t time.Time) string {
+	return time.Date(t.Year(), t.Month(), t.Day(), 8, 0, 0, 0, time.UTC).Format(msProjectTime)
+}
+
+func workFinish(t time.Time) string {
+	return time.Date(t.Year(), t.Month(), t.Day(), 17, 0, 0, 0, time.UTC).Format(msProjectTime)
+}
+
+// msProjectDuration counts the working days from start to finish at eight
+// hours a day
+func msProjectDuration(start time.Time, finish time.Time) string {
+	days := 0
+	for d := start; !d.After(finish); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			days++
+		}
+	}
+	return fmt.Sprintf("PT%dH0M0S", days*8)
+}
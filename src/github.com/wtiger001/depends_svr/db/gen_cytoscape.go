@@ -0,0 +1,40 @@
+//go:build ignore
+// +build ignore
+
+// gen_cytoscape downloads the Cytoscape.js build that the HTML report bundles
+// and writes it to cytoscape_js.go. Run it with go generate in this package.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+const cytoscapeVersion = "3.28.1"
+
+func main() {
+	url := "https://unpkg.com/cytoscape@" + cytoscapeVersion + "/dist/cytoscape.min.js"
+	resp, err := http.Get(url)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		log.Fatalf("%s: %s", url, resp.Status)
+	}
+	js, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by gen_cytoscape.go from %s; DO NOT EDIT.\n\n", url)
+	fmt.Fprintf(&out, "package db\n\nfunc init() {\n\tcytoscapeJS = %q\n}\n", js)
+	if err = ioutil.WriteFile("cytoscape_js.go", out.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote Cytoscape.js %s (%d bytes) to cytoscape_js.go\n", cytoscapeVersion, len(js))
+}
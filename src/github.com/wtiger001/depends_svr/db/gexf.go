@@ -0,0 +1,102 @@
+package db
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+	"strconv"
+)
+
+type gexf struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID     string         `xml:"id,attr"`
+	Label  string         `xml:"label,attr"`
+	Values []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string         `xml:"id,attr"`
+	Source string         `xml:"source,attr"`
+	Target string         `xml:"target,attr"`
+	Label  string         `xml:"label,attr,omitempty"`
+	Weight string         `xml:"weight,attr,omitempty"`
+	Values []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// writeGEXF writes the graph as GEXF 1.2 (Gephi) with every Data field as a
+// typed attribute. Edges to nodes that are not in the graph are left out.
+func (graph *Graph) writeGEXF(w io.Writer) error {
+	doc := gexf{Xmlns: "http://www.gexf.net/1.2draft", Version: "1.2"}
+	doc.Graph.DefaultEdgeType = "directed"
+
+	var attrs []gexfAttribute
+	for i, attr := range dataAttributes {
+		kind := attr.kind
+		if kind == "int" {
+			kind = "integer"
+		}
+		attrs = append(attrs, gexfAttribute{ID: strconv.Itoa(i), Title: attr.name, Type: kind})
+	}
+	doc.Graph.Attributes = []gexfAttributes{
+		{Class: "node", Attributes: attrs},
+		{Class: "edge", Attributes: attrs},
+	}
+
+	skipped := 0
+	for _, item := range graph.Items {
+		var values []gexfAttValue
+		for i, attr := range dataAttributes {
+			if v, ok := attr.value(item.Data); ok {
+				values = append(values, gexfAttValue{For: strconv.Itoa(i), Value: v})
+			}
+		}
+		if item.Group == "nodes" {
+			doc.Graph.Nodes = append(doc.Graph.Nodes, gexfNode{ID: item.Data.Id, Label: item.Data.Label, Values: values})
+		} else if graph.exists(item.Data.Source) && graph.exists(item.Data.Target) {
+			e := gexfEdge{ID: item.Data.Id, Source: item.Data.Source, Target: item.Data.Target, Label: item.Data.Type, Values: values}
+			if item.Data.Weight > 0 {
+				e.Weight = strconv.Itoa(item.Data.Weight)
+			}
+			doc.Graph.Edges = append(doc.Graph.Edges, e)
+		} else {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		log.Printf("Skipped %d edges with missing nodes\n", skipped)
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
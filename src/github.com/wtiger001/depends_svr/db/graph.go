@@ -3,8 +3,11 @@ package db
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
+	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -14,33 +17,63 @@ import (
 )
 
 type Graph struct {
-	Items []*GraphItem `json:"graph"`
-	m     map[string]*GraphItem
+	Items    []*GraphItem `json:"graph"`
+	Run      *Run         `json:"run,omitempty"`
+	Findings []*Violation `json:"findings,omitempty"`
+	m        map[string]*GraphItem
+	teams    map[string]string
+
+	forecastRun *ForecastRun
+}
+
+// Run - When and where the graph was extracted from
+type Run struct {
+	Started  string   `json:"started"`
+	Finished string   `json:"finished"`
+	JiraURL  string   `json:"jira_url"`
+	Projects []string `json:"projects"`
 }
 
 type GraphItem struct {
-	Group string `json:"group"`
-	Data  *Data  `json:"data"`
+	Group    string    `json:"group"`
+	Data     *Data     `json:"data"`
+	Position *Position `json:"position,omitempty"`
 }
 
 type Data struct {
-	Id          string `json:"id,omitempty"`
-	Label       string `json:"label,omitempty"`
-	Parent      string `json:"parent,omitempty"`
-	Source      string `json:"source,omitempty"`
-	Target      string `json:"target,omitempty"`
-	From        string `json:"from,omitempty"`
-	To          string `json:"to,omitempty"`
-	Type        string `json:"type,omitempty"`
-	Degree      int    `json:"degree,omitempty"`
-	Version     string `json:"version,omitempty"`
-	Component   string `json:"component,omitempty"`
-	Status      string `json:"status,omitempty"`
-	StartDate   string `json:"start_date,omitempty"`
-	FinishDate  string `json:"finish_date,omitempty"`
-	Description string `json:"description,omitempty"`
-	typeSource  string
-	typeTarget  string
+	Id              string             `json:"id,omitempty"`
+	Label           string             `json:"label,omitempty"`
+	Parent          string             `json:"parent,omitempty"`
+	Source          string             `json:"source,omitempty"`
+	Target          string             `json:"target,omitempty"`
+	From            string             `json:"from,omitempty"`
+	To              string             `json:"to,omitempty"`
+	Type            string             `json:"type,omitempty"`
+	Degree          int                `json:"degree,omitempty"`
+	Version         string             `json:"version,omitempty"`
+	Component       string             `json:"component,omitempty"`
+	Status          string             `json:"status,omitempty"`
+	Resolution      string             `json:"resolution,omitempty"`
+	StartDate       string             `json:"start_date,omitempty"`
+	FinishDate      string             `json:"finish_date,omitempty"`
+	Description     string             `json:"description,omitempty"`
+	Weight          int                `json:"weight,omitempty"`
+	Issues          []string           `json:"issues,omitempty"`
+	Labels          []string           `json:"labels,omitempty"`
+	Team            string             `json:"team,omitempty"`
+	Warning         string             `json:"warning,omitempty"`
+	Points          float64            `json:"points,omitempty"`
+	Progress        float64            `json:"progress,omitempty"`
+	PointsProgress  float64            `json:"points_progress,omitempty"`
+	DerivedStatus   string             `json:"derived_status,omitempty"`
+	CompletedPoints float64            `json:"completed_points,omitempty"`
+	Velocity        float64            `json:"velocity,omitempty"`
+	IssueTypes      map[string]int     `json:"issue_types,omitempty"`
+	AssigneeIssues  map[string]int     `json:"assignee_issues,omitempty"`
+	AssigneePoints  map[string]float64 `json:"assignee_points,omitempty"`
+	Forecast        *Forecast          `json:"forecast,omitempty"`
+	typeSource      string
+	typeTarget      string
 }
 
 func Node() *GraphItem {
@@ -62,9 +95,31 @@ func Edge() *GraphItem {
 func NewGraph() *Graph {
 	g := new(Graph)
 	g.m = make(map[string]*GraphItem)
+	g.teams = make(map[string]string)
 	return g
 }
 
+// LoadGraph reads a graph back from a JSON output file
+func LoadGraph(file string) (graph *Graph, err error) {
+	defer timeTrack(time.Now(), "Load Graph from "+file)
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	graph = NewGraph()
+	err = json.Unmarshal(raw, graph)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range graph.Items {
+		graph.m[item.Data.Id] = item
+	}
+
+	log.Printf("Loaded %d nodes and edges from %s\n", len(graph.Items), file)
+	return graph, nil
+}
+
 func (graph *Graph) add(item *GraphItem) {
 	item.Data.Id = validID(item.Data.Id)
 	item.Data.Target = validID(item.Data.Target)
@@ -89,6 +144,7 @@ func (graph *Graph) addSprint(sprint *jira.Sprint) {
 		n.Data.FinishDate = sprint.EndDate.String()
 	}
 	n.Data.Status = sprint.State
+	n.Data.Team = graph.teams[n.Data.Id]
 	n.Data.Type = "Sprint"
 	graph.add(n)
 }
@@ -142,7 +198,18 @@ func (graph *Graph) addStatic(issues *IssueList, cfg *JiraConfig) {
 		n.Data.Label = issue.Fields.Summary
 		n.Data.Description = issue.Fields.Description
 		n.Data.Component = first(issue.Fields.Components)
+		n.Data.Version = fixVersionNames(issue.Fields.FixVersions)
+		n.Data.Labels = issue.Fields.Labels
 		n.Data.Type = getNodeType(issue.Fields.Type.Name, cfg)
+		if issue.Fields.Status != nil {
+			n.Data.Status = issue.Fields.Status.Name
+		}
+		if issue.Fields.Resolution != nil {
+			n.Data.Resolution = issue.Fields.Resolution.Name
+		}
+		if points, ok := issue.Fields.Unknowns[cfg.PointsField].(float64); ok {
+			n.Data.Points = points
+		}
 
 		if n.Data.Type == "thread" {
 			a := issue.Fields.Unknowns["customfield_13008"]
@@ -159,6 +226,11 @@ func (graph *Graph) addStatic(issues *IssueList, cfg *JiraConfig) {
 			graph.componentLink(c.Name, n, cfg)
 		}
 
+		// Create the link to the releases
+		for _, v := range issue.Fields.FixVersions {
+			graph.versionLink(v, n)
+		}
+
 		// Create any links
 		for _, link := range issue.Fields.IssueLinks {
 			_, issueType, linkType, _ := linked(link)
@@ -204,10 +276,10 @@ func first(components []*jira.Component) string {
 }
 
 func (graph *Graph) save(cfg *JiraConfig) (err error) {
-	return graph.saveAs(cfg.OutputFile)
+	return graph.saveAs(cfg.OutputFile, cfg)
 }
 
-func (graph *Graph) saveAs(file string) (err error) {
+func (graph *Graph) saveAs(file string, cfg *JiraConfig) (err error) {
 	defer timeTrack(time.Now(), "Save Output as "+file)
 
 	graph.printSummary()
@@ -216,40 +288,103 @@ func (graph *Graph) saveAs(file string) (err error) {
 		return graph.Items[i].Group > graph.Items[j].Group
 	})
 
-	graphJSON, _ := json.Marshal(graph)
-	err = ioutil.WriteFile(file, graphJSON, 0644)
+	err = graph.writeFile(file, outputFormat(file, cfg.OutputFormat), cfg)
 
 	log.Printf("Wrote %s file for database containing %d nodes and edges\n", file, len(graph.Items))
 
 	return err
 }
 
+// writeFile writes the graph to the file in the given format. The database
+// formats manage the file themselves, the rest are streamed through write.
+func (graph *Graph) writeFile(file string, format string, cfg *JiraConfig) (err error) {
+	if format == "sqlite" {
+		return graph.saveSQLite(file)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return graph.write(f, format, cfg)
+}
+
+// outputFormat uses the requested format or else infers it from the file extension
+func outputFormat(file string, format string) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".graphml":
+		return "graphml"
+	case ".gexf":
+		return "gexf"
+	case ".dot", ".gv":
+		return "dot"
+	case ".mmd", ".mermaid":
+		return "mermaid"
+	case ".puml", ".plantuml":
+		return "plantuml"
+	case ".db", ".sqlite", ".sqlite3":
+		return "sqlite"
+	case ".cypher", ".cql":
+		return "cypher"
+	case ".reqif":
+		return "reqif"
+	case ".svg":
+		return "svg"
+	case ".ics":
+		return "ics"
+	case ".gantt":
+		return "gantt"
+	case ".mspdi":
+		return "msproject"
+	}
+	return "json"
+}
+
+// write encodes the graph in one of the supported output formats
+func (graph *Graph) write(w io.Writer, format string, cfg *JiraConfig) (err error) {
+	switch format {
+	case "json":
+		graphJSON, _ := json.Marshal(graph)
+		_, err = w.Write(graphJSON)
+		return err
+	case "graphml":
+		return graph.writeGraphML(w)
+	case "gexf":
+		return graph.writeGEXF(w)
+	case "dot":
+		return graph.writeDOT(w, cfg)
+	case "mermaid":
+		return graph.writeMermaid(w, cfg)
+	case "plantuml":
+		return graph.writePlantUML(w, cfg)
+	case "cypher":
+		return graph.writeCypher(w, cfg)
+	case "reqif":
+		return graph.writeReqIF(w, cfg)
+	case "svg":
+		return graph.writeSVG(w, cfg)
+	case "ics":
+		return graph.writeICal(w, cfg)
+	case "gantt":
+		return graph.writeGantt(w, cfg)
+	case "msproject":
+		return graph.writeMSProject(w, cfg)
+	}
+	return fmt.Errorf("unsupported output format %s", format)
+}
+
+// checkForMissing counts the edges with a missing end. The edges themselves
+// are reported by the structure rule of the validation.
 func (graph *Graph) checkForMissing() {
-	ok := 0
-	bad := 0
+	ok, bad := graph.missingEnds()
 	log.Printf("Checking Node Structure\n")
 	log.Printf("-----------------------------------------\n")
-	for _, item := range graph.Items {
-		if item.Group == "edges" {
-			if item.Data.Target == "" {
-				log.Printf("\tEmpty Target\n")
-				bad++
-			} else if item.Data.Source == "" {
-				log.Printf("\tEmpty Source\n")
-				bad++
-			} else if !graph.exists(item.Data.Target) {
-				log.Printf("\tMising Target Node: %s (%s)\n", item.Data.Target, item.Data.typeTarget)
-				bad++
-			} else if !graph.exists(item.Data.Target) {
-				log.Printf("\tMising Source Node: %s (%s)\n", item.Data.Source, item.Data.typeSource)
-				bad++
-			} else {
-				ok++
-			}
-		}
-	}
-	log.Printf("-----------------------------------------\n")
-	log.Printf("Good: %d, Bad: %d\n", ok, bad)
+	log.Printf("Good: %d, Bad: %d\n", ok, len(bad))
 }
 
 func (graph *Graph) addMissingNodes(cfg *JiraConfig) {
@@ -330,26 +465,37 @@ func (graph *Graph) exists(k string) bool {
 	return ok
 }
 
-func (graph *Graph) printSummary() {
-	nodes, edges := graph.histogram()
+// Summary counts the nodes and edges of each type
+type Summary struct {
+	NodeCount int
+	EdgeCount int
+	Nodes     map[string]int
+	Edges     map[string]int
+}
 
-	sumNodes := 0
-	sumEdges := 0
-	for _, v := range nodes {
-		sumNodes += v
+func (graph *Graph) summary() *Summary {
+	s := new(Summary)
+	s.Nodes, s.Edges = graph.histogram()
+	for _, v := range s.Nodes {
+		s.NodeCount += v
 	}
-	for _, v := range edges {
-		sumEdges += v
+	for _, v := range s.Edges {
+		s.EdgeCount += v
 	}
+	return s
+}
+
+func (graph *Graph) printSummary() {
+	s := graph.summary()
 
 	log.Printf("GRAPH SUMMARY\n")
 	log.Printf("-----------------------------------------\n")
-	log.Printf("%-33s:%6d\n", "Nodes", sumNodes)
-	for k, v := range nodes {
+	log.Printf("%-33s:%6d\n", "Nodes", s.NodeCount)
+	for k, v := range s.Nodes {
 		log.Printf("   %-30s:%6d\n", k, v)
 	}
-	log.Printf("%-33s:%6d\n", "Edges", sumEdges)
-	for k, v := range edges {
+	log.Printf("%-33s:%6d\n", "Edges", s.EdgeCount)
+	for k, v := range s.Edges {
 		log.Printf("   %-30s:%6d\n", k, v)
 	}
 	log.Printf("-----------------------------------------\n")
@@ -362,6 +508,23 @@ func validID(id string) string {
 	return newID
 }
 
+// parseDate reads the date formats found in the graph: the Go time format
+// used for sprints, the JIRA timestamp format and plain dates
+func parseDate(s string) (t time.Time, ok bool) {
+	layouts := []string{
+		"2006-01-02 15:04:05.999999999 -0700 MST",
+		time.RFC3339,
+		"2006-01-02T15:04:05.000-0700",
+		"2006-01-02",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
 func (graph *Graph) histogram() (nodes map[string]int, edges map[string]int) {
 	nodes = make(map[string]int)
 	edges = make(map[string]int)
@@ -0,0 +1,79 @@
+package db
+
+import "testing"
+
+// testGraph builds a graph from nodes and edges made with testNode, testEdge
+// and testSprint
+func testGraph(items ...*GraphItem) *Graph {
+	graph := NewGraph()
+	for _, item := range items {
+		graph.add(item)
+	}
+	return graph
+}
+
+func testNode(id string, nodeType string, start string, finish string) *GraphItem {
+	n := Node()
+	n.Data.Id = id
+	n.Data.Label = id
+	n.Data.Type = nodeType
+	n.Data.StartDate = start
+	n.Data.FinishDate = finish
+	return n
+}
+
+// testEdge links source, the issue depended on, to target
+func testEdge(source string, target string, linkType string) *GraphItem {
+	e := Edge()
+	e.Data.Id = source + "_" + linkType + "_" + target
+	e.Data.Source = source
+	e.Data.Target = target
+	e.Data.Type = linkType
+	return e
+}
+
+// testSprint plans the issue in the sprint
+func testSprint(sprint string, issue string) *GraphItem {
+	e := Edge()
+	e.Data.Id = issue + "_SPRINT_" + sprint
+	e.Data.Source = sprint
+	e.Data.Target = issue
+	e.Data.Type = "depends on"
+	return e
+}
+
+func testConfig() *JiraConfig {
+	cfg := new(JiraConfig)
+	cfg.ApplyDefaults()
+	return cfg
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		file   string
+		format string
+		want   string
+	}{
+		{"output.json", "", "json"},
+		{"output", "", "json"},
+		{"graph.GraphML", "", "graphml"},
+		{"graph.gexf", "", "gexf"},
+		{"graph.gv", "", "dot"},
+		{"graph.mmd", "", "mermaid"},
+		{"graph.puml", "", "plantuml"},
+		{"graph.sqlite3", "", "sqlite"},
+		{"graph.cql", "", "cypher"},
+		{"graph.reqif", "", "reqif"},
+		{"graph.svg", "", "svg"},
+		{"plan.ics", "", "ics"},
+		{"plan.gantt", "", "gantt"},
+		{"plan.mspdi", "", "msproject"},
+		{"graph.svg", "DOT", "dot"},
+		{"dir.v2/output", "", "json"},
+	}
+	for _, tt := range tests {
+		if got := outputFormat(tt.file, tt.format); got != tt.want {
+			t.Errorf("outputFormat(%q, %q) = %q, want %q", tt.file, tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package db
+
+import (
+	"encoding/xml"
+	"io"
+	"log"
+)
+
+type graphML struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+// writeGraphML writes the graph as GraphML (yEd) with every Data field as a
+// typed attribute. Edges to nodes that are not in the graph are left out.
+func (graph *Graph) writeGraphML(w io.Writer) error {
+	doc := graphML{Xmlns: "http://graphml.graphdrawing.org/xmlns"}
+	doc.Graph.ID = "depends"
+	doc.Graph.EdgeDefault = "directed"
+	for _, attr := range dataAttributes {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: "d_" + attr.name, For: "all", Name: attr.name, Type: attr.kind})
+	}
+
+	skipped := 0
+	for _, item := range graph.Items {
+		var data []graphMLData
+		for _, attr := range dataAttributes {
+			if v, ok := attr.value(item.Data); ok {
+				data = append(data, graphMLData{Key: "d_" + attr.name, Value: v})
+			}
+		}
+		if item.Group == "nodes" {
+			doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{ID: item.Data.Id, Data: data})
+		} else if graph.exists(item.Data.Source) && graph.exists(item.Data.Target) {
+			doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{ID: item.Data.Id, Source: item.Data.Source, Target: item.Data.Target, Data: data})
+		} else {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		log.Printf("Skipped %d edges with missing nodes\n", skipped)
+	}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+)
+
+// writeICal writes the sprints and program increments as all day events
+// and the releases and thread deadlines as single day events. Issues are left
+// out of the calendar since they would crowd it.
+func (graph *Graph) writeICal(w io.Writer, cfg *JiraConfig) error {
+	out := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	line := func(s string) {
+		// Lines longer than 75 octets are folded onto continuation lines
+		for len(s) > 75 {
+			cut := 75
+			for cut > 0 && s[cut]&0xC0 == 0x80 {
+				cut--
+			}
+			out.WriteString(s[:cut] + "\r\n")
+			s = " " + s[cut:]
+		}
+		out.WriteString(s + "\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//depends_svr//Depends Schedule//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:Depends Schedule")
+	for _, t := range graph.schedule(cfg) {
+		if t.section == "Issues" {
+			continue
+		}
+		finish := t.finish
+		if finish.Before(t.start) {
+			finish = t.start
+		}
+		line("BEGIN:VEVENT")
+		line("UID:" + icalText(t.id) + "@depends_svr")
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + t.start.Format("20060102"))
+		// The end date of an all day event is exclusive
+		line("DTEND;VALUE=DATE:" + finish.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + icalText(t.name))
+		line("CATEGORIES:" + icalText(t.section))
+		if n, ok := graph.m[t.node]; ok && n.Data.Description != "" {
+			line("DESCRIPTION:" + icalText(n.Data.Description))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+
+	return out.Flush()
+}
+
+func icalText(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+	return r.Replace(s)
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importFiles merges the nodes and edges from other sources into the graph.
+// ReqIF files are read by extension, CSV files are read as edges when they
+// have source and target columns and as nodes otherwise.
+func (graph *Graph) importFiles(files []string, cfg *JiraConfig) {
+	for _, file := range files {
+		var err error
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".reqif", ".xml":
+			err = graph.importReqIF(file, cfg)
+		case ".csv":
+			err = graph.importCSV(file)
+		default:
+			err = fmt.Errorf("unknown import file type")
+		}
+		if err != nil {
+			log.Printf("Unable to import %s: %v\n", file, err)
+		}
+	}
+}
+
+// importCSV reads nodes or edges from a CSV file with a header row. The
+// columns are named after the JSON fields of Data (id, label, type, status,
+// source, target, ...). List columns such as labels are separated by
+// semicolons.
+func (graph *Graph) importCSV(file string) (err error) {
+	defer timeTrack(time.Now(), "Import CSV "+file)
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	_, hasSource := columns["source"]
+	_, hasTarget := columns["target"]
+	edges := hasSource && hasTarget
+	if _, ok := columns["id"]; !ok && !edges {
+		return fmt.Errorf("nodes need an id column")
+	}
+
+	cnt, skipped := 0, 0
+	for number := 2; ; number++ {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		item := Node()
+		if edges {
+			item = Edge()
+		}
+		for name, i := range columns {
+			if i < len(row) && row[i] != "" {
+				setDataField(item.Data, name, row[i])
+			}
+		}
+		if edges && (strings.TrimSpace(item.Data.Source) == "" || strings.TrimSpace(item.Data.Target) == "") {
+			log.Printf("Skipping row %d of %s: the edge needs a source and a target\n", number, file)
+			skipped++
+			continue
+		}
+		if edges && item.Data.Id == "" {
+			item.Data.Id = item.Data.Source + "_" + validID(item.Data.Type) + "_" + item.Data.Target
+		}
+		if strings.TrimSpace(item.Data.Id) == "" {
+			log.Printf("Skipping row %d of %s: the node needs an id\n", number, file)
+			skipped++
+			continue
+		}
+		graph.merge(item)
+		cnt++
+	}
+
+	log.Printf("Imported %d rows from %s, skipped %d\n", cnt, file, skipped)
+	return nil
+}
+
+// merge adds an imported item to the graph. When the graph already has an item
+// with the same id the imported values only fill in the fields that are blank.
+func (graph *Graph) merge(item *GraphItem) {
+	item.Data.Id = validID(item.Data.Id)
+	item.Data.Source = validID(item.Data.Source)
+	item.Data.Target = validID(item.Data.Target)
+
+	existing, ok := graph.m[item.Data.Id]
+	if !ok {
+		graph.add(item)
+		return
+	}
+
+	dst := reflect.ValueOf(existing.Data).Elem()
+	src := reflect.ValueOf(item.Data).Elem()
+	for _, attr := range dataAttributes {
+		if _, set := attr.value(existing.Data); !set {
+			dst.Field(attr.index).Set(src.Field(attr.index))
+		}
+	}
+}
+
+// setDataField sets the Data field with the JSON name from its text form
+func setDataField(d *Data, name string, value string) {
+	switch name {
+	case "id":
+		d.Id = value
+		return
+	case "source":
+		d.Source = value
+		return
+	case "target":
+		d.Target = value
+		return
+	}
+
+	for _, attr := range dataAttributes {
+		if attr.name != name {
+			continue
+		}
+		f := reflect.ValueOf(d).Elem().Field(attr.index)
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(value)
+		case reflect.Int, reflect.Int64:
+			if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+				f.SetInt(v)
+			}
+		case reflect.Float32, reflect.Float64:
+			if v, err := strconv.ParseFloat(value, 64); err == nil {
+				f.SetFloat(v)
+			}
+		case reflect.Bool:
+			if v, err := strconv.ParseBool(value); err == nil {
+				f.SetBool(v)
+			}
+		case reflect.Slice:
+			if f.Type().Elem().Kind() == reflect.String {
+				var list []string
+				for _, v := range strings.Split(value, ";") {
+					if v = strings.TrimSpace(v); v != "" {
+						list = append(list, v)
+					}
+				}
+				f.Set(reflect.ValueOf(list))
+			}
+		}
+		return
+	}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestImportCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want []string
+	}{
+		{
+			name: "nodes without an id are skipped",
+			csv:  "id,label,type\nPIR-1,One,feature\n,No id,feature\n,,\nPIR-2,Two,requirement\n",
+			want: []string{"PIR-1", "PIR-2"},
+		},
+		{
+			name: "edges without both ends are skipped",
+			csv:  "source,target,type\nPIR-1,PIR-2,depends on\n,PIR-2,depends on\nPIR-1,,depends on\n,,\n",
+			want: []string{"PIR-1_depends_on_PIR-2"},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, "import.csv")
+			if err := ioutil.WriteFile(file, []byte(tt.csv), 0644); err != nil {
+				t.Fatal(err)
+			}
+			graph := NewGraph()
+			if err := graph.importCSV(file); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range graph.Items {
+				got = append(got, item.Data.Id)
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("imported %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
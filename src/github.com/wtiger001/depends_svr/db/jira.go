@@ -34,8 +34,41 @@ type JiraData struct {
 
 // ExtractData contacts JIRA and extracts the contents into a database file
 func ExtractData(cfg *JiraConfig) {
+	graph := ExtractGraph(cfg)
+
+	// save the database
+	graph.save(cfg)
+
+	if cfg.HTMLFile != "" {
+		err := graph.saveHTMLReport(cfg.HTMLFile, cfg)
+		if err != nil {
+			log.Printf("Unable to write the HTML report: %v\n", err)
+		}
+	}
+
+	if cfg.SVGFile != "" {
+		err := graph.saveSVG(cfg.SVGFile, cfg)
+		if err != nil {
+			log.Printf("Unable to write the SVG image: %v\n", err)
+		}
+	}
+	if cfg.ForecastFile != "" {
+		err := graph.saveForecast(cfg.ForecastFile, cfg)
+		if err != nil {
+			log.Printf("Unable to write the forecast report: %v\n", err)
+		}
+	}
+}
+
+// ExtractGraph contacts JIRA and builds the graph from its contents
+func ExtractGraph(cfg *JiraConfig) *Graph {
 	// Setup Graph
 	graph := NewGraph()
+	graph.Run = &Run{
+		Started:  time.Now().Format(time.RFC3339),
+		JiraURL:  cfg.JiraURL,
+		Projects: cfg.Projects,
+	}
 
 	// Set up the JIRA Client
 	jiraClient, _ := jira.NewClient(nil, cfg.JiraURL)
@@ -44,17 +77,65 @@ func ExtractData(cfg *JiraConfig) {
 	// Load the components
 	loadComponents(cfg, jiraClient, graph)
 
+	// Load the releases
+	loadVersions(cfg, jiraClient, graph)
+
 	// Load the issue types we consider static
 	loadStaticIssues(cfg, jiraClient, graph)
 
 	// load the sprints
 	loadBoards(cfg, jiraClient, graph)
+	graph.deriveVelocity(cfg)
+
+	// Merge in the ReqIF and CSV files
+	graph.importFiles(cfg.Imports, cfg)
+
+	// Connect the sprints to the releases and check the release order
+	graph.linkSprintVersions()
+	graph.checkVersionOrder(cfg)
+
+	// Roll the progress of the features and requirements up to the
+	// capabilities and threads
+	graph.rollupProgress(cfg)
+
+	// Forecast the threads and capabilities from the sprint throughput
+	graph.forecast(cfg)
+
+	// Roll the issue dependencies up to the components
+	if cfg.ComponentLayer || cfg.DSMFile != "" {
+		matrix := graph.deriveComponentDependencies(cfg)
+		if cfg.ComponentLayer {
+			graph.addComponentLayer(matrix)
+		}
+		if cfg.DSMFile != "" {
+			matrix.saveAs(cfg.DSMFile)
+		}
+	}
+
+	// Derive the dependencies between sprints
+	if cfg.SprintDependencies {
+		graph.deriveSprintDependencies(cfg)
+	}
+
+	// Group the sprints into program increments
+	if len(cfg.ProgramIncrements) > 0 {
+		graph.groupProgramIncrements(cfg)
+		if cfg.ProgramBoardFile != "" {
+			graph.saveProgramBoard(cfg.ProgramBoardFile, cfg)
+		}
+	}
 
 	// graph.addMissingNodes(cfg)
 	// graph.trimMissing(cfg)
 
-	// save the database
-	graph.save(cfg)
+	// Precompute the node positions
+	graph.layout(cfg)
+
+	// Check the graph against the metamodel
+	graph.validate(cfg)
+
+	graph.Run.Finished = time.Now().Format(time.RFC3339)
+	return graph
 }
 
 func loadComponents(cfg *JiraConfig, jiraClient *jira.Client, graph *Graph) (err error) {
@@ -98,16 +179,21 @@ func requestComponents(projectKey string, cfg *JiraConfig, jiraClient *jira.Clie
 }
 
 func loadBoards(cfg *JiraConfig, jiraClient *jira.Client, graph *Graph) (err error) {
-	sprintMap, _ := getBoards(cfg, jiraClient, graph)
+	sprintMap, kanbanBoards, _ := getBoards(cfg, jiraClient, graph)
 
 	log.Printf("%d Scrum Sprints Found\n", len(sprintMap))
 	for _, v := range sprintMap {
 		loadSprint(&v, cfg, jiraClient, graph)
 	}
+
+	log.Printf("%d Kanban Boards Found\n", len(kanbanBoards))
+	for _, board := range kanbanBoards {
+		loadKanbanBoard(&board, cfg, jiraClient, graph)
+	}
 	return nil
 }
 
-func getBoards(cfg *JiraConfig, jiraClient *jira.Client, graph *Graph) (sprintMap map[int]jira.Sprint, err error) {
+func getBoards(cfg *JiraConfig, jiraClient *jira.Client, graph *Graph) (sprintMap map[int]jira.Sprint, kanbanBoards []jira.Board, err error) {
 	defer timeTrack(time.Now(), "Get Boards")
 	startAt := 0
 	pageSize := 100
@@ -130,8 +216,15 @@ func getBoards(cfg *JiraConfig, jiraClient *jira.Client, graph *Graph) (sprintMa
 			// log.Printf("\tBoard : %s\n", board.Name)
 			// log.Printf("%+v\n", board)
 			if board.Type == "kanban" {
-				if cfg.Debug {
-					log.Printf("\tSkipping Board : %s Type: %s ID: %d\n", board.Name, board.Type, board.ID)
+				if cfg.KanbanBuckets == "" {
+					if cfg.Debug {
+						log.Printf("\tSkipping Board : %s Type: %s ID: %d\n", board.Name, board.Type, board.ID)
+					}
+				} else {
+					if cfg.Debug {
+						log.Printf("\tBoard : %s Type: %s ID: %d\n", board.Name, board.Type, board.ID)
+					}
+					kanbanBoards = append(kanbanBoards, board)
 				}
 			} else {
 				if cfg.Debug {
@@ -145,7 +238,7 @@ func getBoards(cfg *JiraConfig, jiraClient *jira.Client, graph *Graph) (sprintMa
 
 		if boards.Total <= (boards.StartAt + boards.MaxResults) {
 			log.Printf("Graph contains %d Items \n\n", len(graph.Items))
-			return sprintMap, nil
+			return sprintMap, kanbanBoards, nil
 		}
 	}
 
@@ -168,6 +261,12 @@ func mapSprints(board *jira.Board, cfg *JiraConfig, jiraClient *jira.Client, gra
 			log.Printf("\t\tSprint : %s\n", sprint.Name)
 		}
 		sprintMap[sprint.ID] = sprint
+
+		// A sprint belongs to the team of the first board it was found on
+		id := strconv.Itoa(sprint.ID)
+		if _, ok := graph.teams[id]; !ok {
+			graph.teams[id] = board.Name
+		}
 	}
 
 	return nil
@@ -187,8 +286,9 @@ func loadSprint(sprint *jira.Sprint, cfg *JiraConfig, jiraClient *jira.Client, g
 	// Aggregate the issues
 	log.Printf("Loading %d issues for Sprint %s\n", len(issues), sprint.Name)
 	for _, issue := range issues {
-		aggregateSprintIssue(sprint, &issue, cfg, graph)
+		aggregateSprintIssue(strconv.Itoa(sprint.ID), &issue, cfg, graph)
 	}
+	graph.addSprintLoad(strconv.Itoa(sprint.ID), issues, cfg)
 	return nil
 }
 
@@ -224,9 +324,40 @@ func trackedLinkType(linkType string, cfg *JiraConfig) bool {
 	return false
 }
 
+// isDependencyLink checks for the depends on and traces link types (in either
+// direction) that carry dependencies between issues
+func isDependencyLink(linkType string, cfg *JiraConfig) bool {
+	switch strings.ToLower(linkType) {
+	case strings.ToLower(cfg.TracesFromLink):
+		return true
+	case strings.ToLower(cfg.TracesToLink):
+		return true
+	case strings.ToLower(cfg.DependsLinkIn):
+		return true
+	case strings.ToLower(cfg.DependsLinkOut):
+		return true
+	}
+	return false
+}
+
+// linkKind groups the link types into the kinds of relationship they express
+func linkKind(linkType string, cfg *JiraConfig) string {
+	switch strings.ToLower(linkType) {
+	case strings.ToLower(cfg.DependsLinkOut), strings.ToLower(cfg.DependsLinkIn):
+		return "depends"
+	case strings.ToLower(cfg.TracesToLink), strings.ToLower(cfg.TracesFromLink):
+		return "traces"
+	case strings.ToLower(cfg.ParentLink), strings.ToLower(cfg.ChildLink):
+		return "parent"
+	case "fixed in", "contributes to":
+		return "release"
+	}
+	return "other"
+}
+
 // aggregateSprintIssue uses the imformation from each issue to determine dependancies on the
 // overall sprint node. The dependency types are Process, Component, Feature, Capability and Requirement
-func aggregateSprintIssue(sprint *jira.Sprint, issue *jira.Issue, cfg *JiraConfig, graph *Graph) {
+func aggregateSprintIssue(sprintID string, issue *jira.Issue, cfg *JiraConfig, graph *Graph) {
 	// Create direct dependency
 	switch issue.Fields.Type.Name {
 	case cfg.CapabilityIssueType:
@@ -236,11 +367,11 @@ func aggregateSprintIssue(sprint *jira.Sprint, issue *jira.Issue, cfg *JiraConfi
 	case cfg.RequirementIssueType:
 		fallthrough
 	case cfg.ThreadIssueType:
-		linkID := validID(issue.Key + "_SPRINT_" + strconv.Itoa(sprint.ID))
+		linkID := validID(issue.Key + "_SPRINT_" + sprintID)
 		if edge, ok := graph.m[linkID]; !ok {
 			edge := Edge()
 			edge.Data.Target = validID(issue.Key)
-			edge.Data.Source = validID(strconv.Itoa(sprint.ID))
+			edge.Data.Source = validID(sprintID)
 			edge.Data.Id = linkID
 			edge.Data.Description = fmt.Sprintf("Issue %s", issue.Key)
 			// edge.Data.Type = getDependencyType(issueType, cfg)
@@ -265,11 +396,11 @@ func aggregateSprintIssue(sprint *jira.Sprint, issue *jira.Issue, cfg *JiraConfi
 			case cfg.RequirementIssueType:
 				fallthrough
 			case cfg.ThreadIssueType:
-				linkID := validID(linked.ID + "_SPRINT_" + strconv.Itoa(sprint.ID))
+				linkID := validID(linked.ID + "_SPRINT_" + sprintID)
 				if edge, ok := graph.m[linkID]; !ok {
 					edge := Edge()
 					edge.Data.Target = validID(linked.Key)
-					edge.Data.Source = validID(strconv.Itoa(sprint.ID))
+					edge.Data.Source = validID(sprintID)
 					edge.Data.Id = linkID
 					edge.Data.Description = fmt.Sprintf("Issue %s link %s", issue.Key, linked.Key)
 					// edge.Data.Type = getDependencyType(issueType, cfg)
@@ -293,11 +424,11 @@ func aggregateSprintIssue(sprint *jira.Sprint, issue *jira.Issue, cfg *JiraConfi
 			// Extract the process name
 			process := strings.TrimPrefix(strings.ToLower(label), strings.ToLower(cfg.ProcessPrefix))
 
-			linkID := validID(process + "_SPRINT_" + strconv.Itoa(sprint.ID))
+			linkID := validID(process + "_SPRINT_" + sprintID)
 			if edge, ok := graph.m[linkID]; !ok {
 				edge := Edge()
 				edge.Data.Target = validID(process)
-				edge.Data.Source = validID(strconv.Itoa(sprint.ID))
+				edge.Data.Source = validID(sprintID)
 				edge.Data.Id = linkID
 				edge.Data.Description = fmt.Sprintf("Issue %s process label %s", issue.Key, label)
 				edge.Data.Type = cfg.DependsLinkOut
@@ -341,7 +472,10 @@ func requestStaticIssues(cfg *JiraConfig, jiraClient *jira.Client, startAt int,
 	opts.JQL = makeJql(cfg)
 	opts.StartAt = startAt
 	opts.MaxResults = pageSize
-	opts.Fields = []string{"summary", "issuetype", "status", "components", "labels", "issuelinks", "description", "customfield_13008"}
+	opts.Fields = []string{"summary", "issuetype", "status", "resolution", "components", "labels", "issuelinks", "description", "fixVersions", "customfield_13008"}
+	if cfg.PointsField != "" {
+		opts.Fields = append(opts.Fields, cfg.PointsField)
+	}
 
 	req, _ := jiraClient.NewRequest("POST", "rest/api/2/search", opts)
 
@@ -0,0 +1,232 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http/httputil"
+	"sort"
+	"strconv"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+// kanbanBucket is a group of kanban issues that stands in for a sprint
+type kanbanBucket struct {
+	key    string
+	name   string
+	start  time.Time
+	finish time.Time
+	state  string
+	issues []jira.Issue
+}
+
+// loadKanbanBoard pulls the issues of a kanban board through the board's
+// filter and groups them into buckets by fix version, date window or board
+// column. Each bucket is added as a pseudo sprint so the kanban teams'
+// dependencies are captured the same way as the scrum teams'.
+func loadKanbanBoard(board *jira.Board, cfg *JiraConfig, jiraClient *jira.Client, graph *Graph) (err error) {
+	defer timeTrack(time.Now(), "Load Kanban Board "+board.Name)
+
+	issues, err := requestBoardIssues(board.ID, cfg, jiraClient)
+	if err != nil {
+		log.Printf("Unable to load the issues for Board %s: %v\n", board.Name, err)
+		return err
+	}
+
+	var buckets []*kanbanBucket
+	switch cfg.KanbanBuckets {
+	case "fixversion":
+		buckets = bucketByFixVersion(issues)
+	case "date":
+		buckets = bucketByDate(issues, cfg)
+	case "column":
+		buckets, err = bucketByColumn(board, issues, jiraClient)
+		if err != nil {
+			log.Printf("Unable to load the columns for Board %s: %v\n", board.Name, err)
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown kanban bucket type %s", cfg.KanbanBuckets)
+	}
+
+	log.Printf("Loading %d issues in %d buckets for Kanban Board %s\n", len(issues), len(buckets), board.Name)
+	for _, bucket := range buckets {
+		id := validID("KANBAN_" + strconv.Itoa(board.ID) + "_" + bucket.key)
+		graph.addKanbanBucket(id, board, bucket)
+		for _, issue := range bucket.issues {
+			aggregateSprintIssue(id, &issue, cfg, graph)
+		}
+	}
+	return nil
+}
+
+func (graph *Graph) addKanbanBucket(id string, board *jira.Board, bucket *kanbanBucket) {
+	n := Node()
+	n.Data.Id = id
+	n.Data.Label = board.Name + " " + bucket.name
+	if !bucket.start.IsZero() {
+		n.Data.StartDate = bucket.start.String()
+	}
+	if !bucket.finish.IsZero() {
+		n.Data.FinishDate = bucket.finish.String()
+	}
+	n.Data.Status = bucket.state
+	n.Data.Team = board.Name
+	n.Data.Type = "Sprint"
+	graph.add(n)
+}
+
+// requestBoardIssues pages through the issues selected by the board's filter
+func requestBoardIssues(boardID int, cfg *JiraConfig, jiraClient *jira.Client) (issues []jira.Issue, err error) {
+	startAt := 0
+	pageSize := 100
+	fields := "summary,issuetype,status,components,labels,issuelinks,fixVersions,resolutiondate,updated"
+	for {
+		url := fmt.Sprintf("rest/agile/1.0/board/%d/issue?startAt=%d&maxResults=%d&fields=%s", boardID, startAt, pageSize, fields)
+		req, _ := jiraClient.NewRequest("GET", url, nil)
+
+		// Save a copy of this request for debugging.
+		if cfg.Debug {
+			requestDump, err := httputil.DumpRequest(req, true)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Println(string(requestDump))
+		}
+
+		page := new(IssueList)
+		_, err = jiraClient.Do(req, page)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, page.Issues...)
+
+		startAt = page.StartAt + page.MaxResults
+		if page.Total <= startAt || len(page.Issues) == 0 {
+			return issues, nil
+		}
+	}
+}
+
+// bucketByFixVersion groups the issues by their first fix version. Issues
+// without a fix version are collected in an unscheduled bucket.
+func bucketByFixVersion(issues []jira.Issue) []*kanbanBucket {
+	buckets := make(map[string]*kanbanBucket)
+	for _, issue := range issues {
+		key := "Unscheduled"
+		var version *jira.FixVersion
+		if len(issue.Fields.FixVersions) > 0 {
+			version = issue.Fields.FixVersions[0]
+			key = version.Name
+		}
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &kanbanBucket{key: key, name: key, state: "future"}
+			if version != nil {
+				bucket.start, _ = parseDate(version.StartDate)
+				bucket.finish, _ = parseDate(version.ReleaseDate)
+				bucket.state = bucketState(bucket.start, version.Released != nil && *version.Released)
+			}
+			buckets[key] = bucket
+		}
+		bucket.issues = append(bucket.issues, issue)
+	}
+	return sortBuckets(buckets)
+}
+
+// bucketByDate groups the issues into fixed length windows counted from the
+// configured epoch. Resolved issues fall in the window they were resolved in
+// and unresolved issues in the current window.
+func bucketByDate(issues []jira.Issue, cfg *JiraConfig) []*kanbanBucket {
+	epoch, ok := parseDate(cfg.KanbanEpoch)
+	if !ok {
+		log.Printf("Invalid kanban epoch %s\n", cfg.KanbanEpoch)
+		return nil
+	}
+	window := time.Duration(cfg.KanbanWindowDays) * 24 * time.Hour
+	now := time.Now()
+
+	buckets := make(map[string]*kanbanBucket)
+	for _, issue := range issues {
+		date := now
+		if resolved := time.Time(issue.Fields.Resolutiondate); !resolved.IsZero() {
+			date = resolved
+		}
+		// Dates before the epoch fall in the windows counted back from it
+		index := int(math.Floor(float64(date.Sub(epoch)) / float64(window)))
+		start := epoch.Add(time.Duration(index) * window)
+		finish := start.Add(window)
+
+		key := start.Format("2006-01-02")
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &kanbanBucket{key: key, name: key, start: start, finish: finish}
+			bucket.state = bucketState(start, finish.Before(now))
+			buckets[key] = bucket
+		}
+		bucket.issues = append(bucket.issues, issue)
+	}
+	return sortBuckets(buckets)
+}
+
+// bucketByColumn groups the issues by the board column their status maps to
+func bucketByColumn(board *jira.Board, issues []jira.Issue, jiraClient *jira.Client) ([]*kanbanBucket, error) {
+	config, _, err := jiraClient.Board.GetBoardConfiguration(board.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]string)
+	order := make(map[string]int)
+	for i, column := range config.ColumnConfig.Columns {
+		order[column.Name] = i
+		for _, status := range column.Status {
+			columns[status.ID] = column.Name
+		}
+	}
+
+	buckets := make(map[string]*kanbanBucket)
+	for _, issue := range issues {
+		if issue.Fields.Status == nil {
+			continue
+		}
+		name, ok := columns[issue.Fields.Status.ID]
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%02d_%s", order[name], name)
+		bucket, ok := buckets[key]
+		if !ok {
+			bucket = &kanbanBucket{key: key, name: name, state: "active"}
+			buckets[key] = bucket
+		}
+		bucket.issues = append(bucket.issues, issue)
+	}
+	return sortBuckets(buckets), nil
+}
+
+// bucketState mirrors the sprint states for a bucket
+func bucketState(start time.Time, closed bool) string {
+	now := time.Now()
+	switch {
+	case closed:
+		return "closed"
+	case !start.IsZero() && start.After(now):
+		return "future"
+	default:
+		return "active"
+	}
+}
+
+func sortBuckets(buckets map[string]*kanbanBucket) []*kanbanBucket {
+	list := make([]*kanbanBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		list = append(list, bucket)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].key < list[j].key
+	})
+	return list
+}
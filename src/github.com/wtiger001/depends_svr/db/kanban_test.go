@@ -0,0 +1,67 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func TestBucketByDate(t *testing.T) {
+	resolved := func(date string) jira.Issue {
+		d, _ := time.Parse("2006-01-02", date)
+		return jira.Issue{Key: date, Fields: &jira.IssueFields{Resolutiondate: jira.Time(d)}}
+	}
+	tests := []struct {
+		name   string
+		days   int
+		issues []jira.Issue
+		want   []string
+	}{
+		{
+			name:   "resolved issues fall in their window",
+			days:   14,
+			issues: []jira.Issue{resolved("2017-01-02"), resolved("2017-01-15"), resolved("2017-01-16")},
+			want:   []string{"2017-01-02 2017-01-16 closed 2", "2017-01-16 2017-01-30 closed 1"},
+		},
+		{
+			name:   "issues before the epoch fall in the windows before it",
+			days:   14,
+			issues: []jira.Issue{resolved("2016-12-19"), resolved("2017-01-01"), resolved("2016-12-18")},
+			want:   []string{"2016-12-05 2016-12-19 closed 1", "2016-12-19 2017-01-02 closed 2"},
+		},
+		{
+			name:   "a window that is not positive uses the default",
+			days:   -7,
+			issues: []jira.Issue{resolved("2017-01-10")},
+			want:   []string{"2017-01-02 2017-01-16 closed 1"},
+		},
+		{
+			name:   "unresolved issues are in the current window",
+			days:   7,
+			issues: []jira.Issue{{Key: "open", Fields: &jira.IssueFields{}}},
+			want:   []string{"current active 1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &JiraConfig{KanbanEpoch: "2017-01-02", KanbanWindowDays: tt.days}
+			cfg.ApplyDefaults()
+			var got []string
+			for _, b := range bucketByDate(tt.issues, cfg) {
+				now := time.Now()
+				span := b.start.Format("2006-01-02") + " " + b.finish.Format("2006-01-02")
+				if !now.Before(b.start) && now.Before(b.finish) {
+					span = "current"
+				}
+				got = append(got, fmt.Sprintf("%s %s %d", span, b.state, len(b.issues)))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bucketByDate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
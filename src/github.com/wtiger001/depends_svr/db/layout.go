@@ -0,0 +1,305 @@
+package db
+
+import (
+	"log"
+	"sort"
+	"time"
+)
+
+// Position is a precomputed node position that Cytoscape uses with the
+// preset layout
+type Position struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+const (
+	layoutNodeSpacing  = 80.0
+	layoutLayerSpacing = 150.0
+	layoutDayWidth     = 20.0
+	layoutRowHeight    = 40.0
+
+	layoutMaxLayerWidth = 60
+)
+
+// layout positions the nodes with the configured layout. The layouts only
+// depend on the ids, links and dates so the positions are the same every run.
+func (graph *Graph) layout(cfg *JiraConfig) {
+	switch cfg.Layout {
+	case "":
+		return
+	case "layered":
+		graph.layeredLayout()
+	case "timeline":
+		graph.timelineLayout()
+	default:
+		log.Printf("Unknown layout %s\n", cfg.Layout)
+	}
+}
+
+// layeredLayout is a Sugiyama style layout. Cycles are broken by reversing
+// the edges that close them, nodes are put in the layer after their deepest
+// predecessor so every edge points down, and the order within the layers is
+// improved with barycenter sweeps to reduce crossings.
+func (graph *Graph) layeredLayout() {
+	defer timeTrack(time.Now(), "Layered Layout")
+
+	var ids []string
+	for _, item := range graph.Items {
+		if item.Group == "nodes" {
+			ids = append(ids, item.Data.Id)
+		}
+	}
+	sort.Strings(ids)
+
+	succ := make(map[string][]string)
+	for _, item := range graph.Items {
+		s, t := item.Data.Source, item.Data.Target
+		if item.Group == "edges" && s != t && graph.exists(s) && graph.exists(t) {
+			succ[s] = appendUnique(succ[s], t)
+		}
+	}
+	for _, id := range ids {
+		sort.Strings(succ[id])
+	}
+
+	// Depth first search in id order, dropping the edges back onto the path
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int)
+	var acyclic [][2]string
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = onPath
+		for _, t := range succ[id] {
+			switch state[t] {
+			case unvisited:
+				acyclic = append(acyclic, [2]string{id, t})
+				visit(t)
+			case done:
+				acyclic = append(acyclic, [2]string{id, t})
+			case onPath:
+				acyclic = append(acyclic, [2]string{t, id})
+			}
+		}
+		state[id] = done
+	}
+	for _, id := range ids {
+		if state[id] == unvisited {
+			visit(id)
+		}
+	}
+
+	preds := make(map[string][]string)
+	succs := make(map[string][]string)
+	indegree := make(map[string]int)
+	for _, e := range acyclic {
+		succs[e[0]] = append(succs[e[0]], e[1])
+		preds[e[1]] = append(preds[e[1]], e[0])
+		indegree[e[1]]++
+	}
+
+	// Longest path layering in topological order
+	layerOf := make(map[string]int)
+	var queue []string
+	for _, id := range ids {
+		if indegree[id] == 0 {
+			queue = append(queue, id)
+		}
+	}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, t := range succs[id] {
+			if layerOf[id]+1 > layerOf[t] {
+				layerOf[t] = layerOf[id] + 1
+			}
+			if indegree[t]--; indegree[t] == 0 {
+				queue = append(queue, t)
+			}
+		}
+	}
+
+	var layers [][]string
+	for _, id := range ids {
+		l := layerOf[id]
+		for len(layers) <= l {
+			layers = append(layers, nil)
+		}
+		layers[l] = append(layers[l], id)
+	}
+
+	order := make(map[string]int)
+	renumber := func(layer []string) {
+		for i, id := range layer {
+			order[id] = i
+		}
+	}
+	for _, layer := range layers {
+		renumber(layer)
+	}
+	sweep := func(layer []string, neighbors map[string][]string) {
+		key := make(map[string]float64)
+		for _, id := range layer {
+			key[id] = float64(order[id])
+			if len(neighbors[id]) > 0 {
+				sum := 0.0
+				for _, n := range neighbors[id] {
+					sum += float64(order[n])
+				}
+				key[id] = sum / float64(len(neighbors[id]))
+			}
+		}
+		sort.SliceStable(layer, func(i, j int) bool {
+			if key[layer[i]] != key[layer[j]] {
+				return key[layer[i]] < key[layer[j]]
+			}
+			return layer[i] < layer[j]
+		})
+		renumber(layer)
+	}
+	for i := 0; i < 4; i++ {
+		for l := 1; l < len(layers); l++ {
+			sweep(layers[l], preds)
+		}
+		for l := len(layers) - 2; l >= 0; l-- {
+			sweep(layers[l], succs)
+		}
+	}
+
+	// Wide layers wrap onto extra rows so the graph keeps a usable shape
+	y := 0.0
+	for _, layer := range layers {
+		width := len(layer)
+		if width > layoutMaxLayerWidth {
+			width = layoutMaxLayerWidth
+		}
+		for i, id := range layer {
+			graph.m[id].Position = &Position{
+				X: (float64(i%layoutMaxLayerWidth) - float64(width-1)/2) * layoutNodeSpacing,
+				Y: y + float64(i/layoutMaxLayerWidth)*layoutRowHeight*1.5,
+			}
+		}
+		y += float64((len(layer)-1)/layoutMaxLayerWidth)*layoutRowHeight*1.5 + layoutLayerSpacing
+	}
+	log.Printf("Layered %d nodes into %d layers\n", len(ids), len(layers))
+}
+
+// timelineLayout places the sprints, releases and program increments along
+// the x axis by date. Each team has a lane with its sprints on top and the
+// issues stacked below the last sprint they were planned in. Everything else
+// is stacked in columns before the first date.
+func (graph *Graph) timelineLayout() {
+	defer timeTrack(time.Now(), "Timeline Layout")
+
+	dateOf := func(d *Data) (time.Time, bool) {
+		if t, ok := parseDate(d.StartDate); ok {
+			return t, true
+		}
+		return parseDate(d.FinishDate)
+	}
+
+	var epoch time.Time
+	dated := make(map[string]time.Time)
+	for _, item := range graph.Items {
+		if item.Group != "nodes" {
+			continue
+		}
+		switch item.Data.Type {
+		case "Sprint", "version", "pi":
+			if t, ok := dateOf(item.Data); ok {
+				dated[item.Data.Id] = t
+				if epoch.IsZero() || t.Before(epoch) {
+					epoch = t
+				}
+			}
+		}
+	}
+	xOf := func(id string) float64 {
+		return dated[id].Sub(epoch).Hours() / 24 * layoutDayWidth
+	}
+
+	// Issues go under the last of their sprints
+	_, sprintsOf := graph.sprintMembership()
+	placedIn := make(map[string]string)
+	for issue, list := range sprintsOf {
+		for _, sprint := range list {
+			if _, ok := dated[sprint]; !ok {
+				continue
+			}
+			cur, ok := placedIn[issue]
+			if !ok || sprintBefore(graph.m[cur], graph.m[sprint]) || !sprintBefore(graph.m[sprint], graph.m[cur]) && cur < sprint {
+				placedIn[issue] = sprint
+			}
+		}
+	}
+
+	var teams []string
+	for id := range dated {
+		if graph.m[id].Data.Type == "Sprint" {
+			teams = appendUnique(teams, graph.m[id].Data.Team)
+		}
+	}
+	sort.Strings(teams)
+
+	// Program increments and releases have the first two rows
+	stacked := make(map[string][]string)
+	var undated []string
+	for _, item := range graph.Items {
+		if item.Group != "nodes" {
+			continue
+		}
+		id := item.Data.Id
+		if _, ok := dated[id]; ok {
+			if item.Data.Type != "Sprint" {
+				stacked[item.Data.Type] = append(stacked[item.Data.Type], id)
+			}
+		} else if sprint, ok := placedIn[id]; ok {
+			stacked[sprint] = append(stacked[sprint], id)
+		} else {
+			undated = append(undated, id)
+		}
+	}
+	for _, list := range stacked {
+		sort.Strings(list)
+	}
+	for i, t := range []string{"pi", "version"} {
+		for _, id := range stacked[t] {
+			graph.m[id].Position = &Position{X: xOf(id), Y: float64(i) * layoutRowHeight}
+		}
+	}
+
+	y := 3 * layoutRowHeight
+	for _, team := range teams {
+		deepest := 0
+		for id := range dated {
+			sprint := graph.m[id]
+			if sprint.Data.Type != "Sprint" || sprint.Data.Team != team {
+				continue
+			}
+			sprint.Position = &Position{X: xOf(id), Y: y}
+			for i, issue := range stacked[id] {
+				graph.m[issue].Position = &Position{X: xOf(id), Y: y + float64(i+1)*layoutRowHeight}
+			}
+			if len(stacked[id]) > deepest {
+				deepest = len(stacked[id])
+			}
+		}
+		y += float64(deepest+2) * layoutRowHeight
+	}
+
+	sort.Slice(undated, func(i, j int) bool {
+		a, b := graph.m[undated[i]].Data, graph.m[undated[j]].Data
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Id < b.Id
+	})
+	for i, id := range undated {
+		graph.m[id].Position = &Position{X: -float64(i/40+2) * 2 * layoutNodeSpacing, Y: float64(i%40) * layoutRowHeight}
+	}
+	log.Printf("Placed %d dated nodes in %d team lanes, %d nodes undated\n", len(dated), len(teams), len(undated))
+}
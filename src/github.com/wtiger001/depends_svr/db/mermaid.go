@@ -0,0 +1,83 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mermaidShapes wraps the node label in the shape for its node type
+var mermaidShapes = map[string][2]string{
+	"capability":  {"[[", "]]"},
+	"feature":     {"(", ")"},
+	"requirement": {"[/", "/]"},
+	"thread":      {"{{", "}}"},
+	"Sprint":      {"[(", ")]"},
+	"component":   {"[", "]"},
+	"version":     {">", "]"},
+	"pi":          {"[", "]"},
+}
+
+var mermaidColors = map[string]string{
+	"capability":  "#f4cccc",
+	"feature":     "#cfe2f3",
+	"requirement": "#d9ead3",
+	"thread":      "#fff2cc",
+	"Sprint":      "#d9d2e9",
+	"component":   "#eeeeee",
+	"version":     "#fce5cd",
+	"pi":          "#ead1dc",
+}
+
+// writeMermaid writes the graph as a Mermaid flowchart
+func (graph *Graph) writeMermaid(w io.Writer, cfg *JiraConfig) error {
+	out := bufio.NewWriter(w)
+	ids := graph.diagramIDs()
+
+	fmt.Fprintf(out, "flowchart LR\n")
+	types := make(map[string]bool)
+	for _, item := range graph.Items {
+		if item.Group != "nodes" {
+			continue
+		}
+		shape, ok := mermaidShapes[item.Data.Type]
+		if !ok {
+			shape = [2]string{"[", "]"}
+		}
+		fmt.Fprintf(out, "  %s%s\"%s\"%s\n", ids[item.Data.Id], shape[0], mermaidEscape(diagramLabel(item)), shape[1])
+		if _, ok := mermaidColors[item.Data.Type]; ok {
+			fmt.Fprintf(out, "  class %s %s\n", ids[item.Data.Id], item.Data.Type)
+			types[item.Data.Type] = true
+		}
+	}
+
+	for _, item := range graph.Items {
+		src, ok1 := ids[item.Data.Source]
+		tgt, ok2 := ids[item.Data.Target]
+		if item.Group != "edges" || !ok1 || !ok2 {
+			continue
+		}
+		arrow := "-->"
+		switch linkKind(item.Data.Type, cfg) {
+		case "traces", "release":
+			arrow = "-.->"
+		case "parent":
+			arrow = "==>"
+		}
+		fmt.Fprintf(out, "  %s %s|\"%s\"| %s\n", src, arrow, mermaidEscape(item.Data.Type), tgt)
+	}
+
+	for _, t := range sortedKeys(mermaidColors) {
+		if types[t] {
+			fmt.Fprintf(out, "  classDef %s fill:%s,stroke:#333\n", t, mermaidColors[t])
+		}
+	}
+	return out.Flush()
+}
+
+func mermaidEscape(s string) string {
+	s = strings.Replace(s, "\"", "#quot;", -1)
+	s = strings.Replace(s, "\r", "", -1)
+	return strings.Replace(s, "\n", " ", -1)
+}
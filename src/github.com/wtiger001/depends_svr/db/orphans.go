@@ -0,0 +1,101 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// findOrphans reports the capabilities without features, the features
+// without requirements and the requirements that are not planned in any
+// sprint. Parent and trace links count from either end since teams make them
+// both ways.
+func (graph *Graph) findOrphans(cfg *JiraConfig) (violations []*Violation) {
+	linked := make(map[string]map[string]bool)
+	mark := func(id string, nodeType string) {
+		if linked[id] == nil {
+			linked[id] = make(map[string]bool)
+		}
+		linked[id][nodeType] = true
+	}
+	for _, item := range graph.Items {
+		src, ok1 := graph.m[item.Data.Source]
+		tgt, ok2 := graph.m[item.Data.Target]
+		if item.Group != "edges" || !ok1 || !ok2 || src == tgt {
+			continue
+		}
+		kind := linkKind(item.Data.Type, cfg)
+		if kind != "parent" && kind != "traces" {
+			continue
+		}
+		mark(src.Data.Id, tgt.Data.Type)
+		mark(tgt.Data.Id, src.Data.Type)
+	}
+	_, sprintsOf := graph.sprintMembership()
+
+	for _, item := range graph.Items {
+		if item.Group != "nodes" {
+			continue
+		}
+		d := item.Data
+		v := &Violation{Severity: "info", Subject: d.Id}
+		switch {
+		case d.Type == "capability" && !linked[d.Id]["feature"]:
+			v.Rule, v.Message = "capability-without-features", "no feature is linked to the capability"
+		case d.Type == "feature" && !linked[d.Id]["requirement"]:
+			v.Rule, v.Message = "feature-without-requirements", "no requirement is linked to the feature"
+		case d.Type == "requirement" && len(sprintsOf[d.Id]) == 0:
+			v.Rule, v.Message = "requirement-without-sprint", "the requirement is not planned in any sprint"
+		default:
+			continue
+		}
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// findStaleDependencies reports the dependencies on issues that are already
+// closed or cancelled, which no longer hold anything up, and warns about the
+// open issues that depend on a cancelled one since they may never be able to
+// finish
+func (graph *Graph) findStaleDependencies(cfg *JiraConfig) (violations []*Violation) {
+	for _, item := range graph.Items {
+		// Sprint membership edges share the dependency link name
+		if item.Group != "edges" || !isDependencyLink(item.Data.Type, cfg) || !graph.isIssue(item.Data.Source) || !graph.isIssue(item.Data.Target) {
+			continue
+		}
+		// The source is the issue that is depended on
+		provider := graph.m[item.Data.Source].Data
+		dependent := graph.m[item.Data.Target].Data
+		state := issueState(provider, cfg)
+		if state == "open" {
+			continue
+		}
+
+		v := &Violation{Rule: "stale-dependency", Severity: "info", Subject: dependent.Id}
+		if state == "cancelled" && issueState(dependent, cfg) == "open" {
+			v.Rule, v.Severity = "depends-on-cancelled", "warning"
+		}
+		v.Message = fmt.Sprintf("depends on %s which is %s", provider.Id, firstOf(provider.Resolution, provider.Status))
+		violations = append(violations, v)
+	}
+	return violations
+}
+
+// issueState sorts an issue into open, closed or cancelled by its status and
+// resolution. A cancelled resolution wins over a closed status.
+func issueState(d *Data, cfg *JiraConfig) string {
+	for _, s := range []string{d.Resolution, d.Status} {
+		if s != "" && containsType(cfg.CancelledStatuses, s) {
+			return "cancelled"
+		}
+	}
+	for _, s := range []string{d.Resolution, d.Status} {
+		if s != "" && containsType(cfg.ClosedStatuses, s) {
+			return "closed"
+		}
+	}
+	if d.Resolution != "" && !strings.EqualFold(d.Resolution, "Unresolved") {
+		return "closed"
+	}
+	return "open"
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// plantUMLShapes gives each node type a PlantUML element
+var plantUMLShapes = map[string]string{
+	"capability":  "package",
+	"feature":     "rectangle",
+	"requirement": "card",
+	"thread":      "hexagon",
+	"Sprint":      "queue",
+	"component":   "component",
+	"version":     "folder",
+	"pi":          "frame",
+}
+
+// writePlantUML writes the graph as a PlantUML diagram
+func (graph *Graph) writePlantUML(w io.Writer, cfg *JiraConfig) error {
+	out := bufio.NewWriter(w)
+	ids := graph.diagramIDs()
+
+	fmt.Fprintf(out, "@startuml\n")
+	fmt.Fprintf(out, "left to right direction\n")
+	for _, t := range sortedKeys(mermaidColors) {
+		fmt.Fprintf(out, "skinparam BackgroundColor<<%s>> %s\n", t, mermaidColors[t])
+	}
+
+	for _, item := range graph.Items {
+		if item.Group != "nodes" {
+			continue
+		}
+		shape, ok := plantUMLShapes[item.Data.Type]
+		if !ok {
+			shape = "rectangle"
+		}
+		fmt.Fprintf(out, "%s \"%s\" as %s <<%s>>\n", shape, plantUMLEscape(diagramLabel(item)), ids[item.Data.Id], item.Data.Type)
+	}
+
+	for _, item := range graph.Items {
+		src, ok1 := ids[item.Data.Source]
+		tgt, ok2 := ids[item.Data.Target]
+		if item.Group != "edges" || !ok1 || !ok2 {
+			continue
+		}
+		arrow := "-->"
+		switch linkKind(item.Data.Type, cfg) {
+		case "traces", "release":
+			arrow = "..>"
+		case "parent":
+			arrow = "*--"
+		}
+		fmt.Fprintf(out, "%s %s %s : %s\n", src, arrow, tgt, plantUMLEscape(item.Data.Type))
+	}
+
+	fmt.Fprintf(out, "@enduml\n")
+	return out.Flush()
+}
+
+func plantUMLEscape(s string) string {
+	s = strings.Replace(s, "\"", "'", -1)
+	s = strings.Replace(s, "\r", "", -1)
+	return strings.Replace(s, "\n", "\\n", -1)
+}
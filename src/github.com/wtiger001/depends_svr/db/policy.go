@@ -0,0 +1,341 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// policy is a check registered by a Starlark policy file with rule(). The
+// check is called with every node (or edge) of the listed types.
+type policy struct {
+	name     string
+	severity string
+	on       string
+	types    []string
+	check    starlark.Callable
+}
+
+// policyIndex holds the edges at each node so the checks can walk to the
+// neighbors
+type policyIndex struct {
+	graph *Graph
+	cfg   *JiraConfig
+	in    map[string][]*GraphItem
+	out   map[string][]*GraphItem
+}
+
+// checkPolicies runs the policy files and reports what their checks return
+func (graph *Graph) checkPolicies(cfg *JiraConfig) (violations []*Violation) {
+	if len(cfg.Policies) == 0 {
+		return nil
+	}
+	defer timeTrack(time.Now(), "Policies")
+
+	idx := &policyIndex{graph: graph, cfg: cfg, in: make(map[string][]*GraphItem), out: make(map[string][]*GraphItem)}
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !graph.exists(item.Data.Source) || !graph.exists(item.Data.Target) {
+			continue
+		}
+		idx.out[item.Data.Source] = append(idx.out[item.Data.Source], item)
+		idx.in[item.Data.Target] = append(idx.in[item.Data.Target], item)
+	}
+
+	for _, file := range cfg.Policies {
+		policies, err := loadPolicy(file)
+		if err != nil {
+			log.Printf("Policy %s: %v\n", file, err)
+			violations = append(violations, &Violation{Rule: "policy", Severity: "error", Subject: file, Message: err.Error()})
+			continue
+		}
+		for _, p := range policies {
+			violations = append(violations, graph.runPolicy(p, idx)...)
+		}
+	}
+	return violations
+}
+
+// loadPolicy executes a policy file and collects the rules it registers
+func loadPolicy(file string) ([]*policy, error) {
+	var policies []*policy
+	rule := func(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		p := &policy{severity: "error", on: "nodes"}
+		var types *starlark.List
+		if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &p.name, "check", &p.check, "severity?", &p.severity, "on?", &p.on, "types?", &types); err != nil {
+			return nil, err
+		}
+		if p.on != "nodes" && p.on != "edges" {
+			return nil, fmt.Errorf("%s: on must be nodes or edges, not %q", fn.Name(), p.on)
+		}
+		if types != nil {
+			for i := 0; i < types.Len(); i++ {
+				s, ok := starlark.AsString(types.Index(i))
+				if !ok {
+					return nil, fmt.Errorf("%s: types must be strings", fn.Name())
+				}
+				p.types = append(p.types, s)
+			}
+		}
+		policies = append(policies, p)
+		return starlark.None, nil
+	}
+
+	thread := &starlark.Thread{Name: file, Print: func(_ *starlark.Thread, msg string) { log.Printf("%s: %s\n", file, msg) }}
+	predeclared := starlark.StringDict{
+		"rule":       starlark.NewBuiltin("rule", rule),
+		"days_until": starlark.NewBuiltin("days_until", policyDaysUntil),
+		"today":      starlark.String(time.Now().Format("2006-01-02")),
+	}
+	if _, err := starlark.ExecFile(thread, file, nil, predeclared); err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, fmt.Errorf("no rules registered")
+	}
+	return policies, nil
+}
+
+// runPolicy calls the check of the policy for each item it applies to. A
+// check passes by returning None, False or an empty string and fails by
+// returning True, a message or a list of messages. The first error stops
+// the policy.
+func (graph *Graph) runPolicy(p *policy, idx *policyIndex) (violations []*Violation) {
+	thread := &starlark.Thread{Name: p.name}
+	for _, item := range graph.Items {
+		if item.Group != p.on || (len(p.types) > 0 && !containsType(p.types, item.Data.Type)) {
+			continue
+		}
+		result, err := starlark.Call(thread, p.check, starlark.Tuple{&policyItem{item: item, idx: idx}}, nil)
+		if err != nil {
+			log.Printf("Policy %s failed on %s: %v\n", p.name, item.Data.Id, err)
+			return append(violations, &Violation{Rule: p.name, Severity: "error", Subject: item.Data.Id, Message: "policy error: " + err.Error()})
+		}
+
+		var messages []string
+		switch r := result.(type) {
+		case starlark.String:
+			messages = append(messages, string(r))
+		case starlark.Bool:
+			if r {
+				messages = append(messages, "fails "+p.name)
+			}
+		case starlark.Indexable:
+			for i := 0; i < r.Len(); i++ {
+				if s, ok := starlark.AsString(r.Index(i)); ok {
+					messages = append(messages, s)
+				} else {
+					messages = append(messages, r.Index(i).String())
+				}
+			}
+		}
+		for _, msg := range messages {
+			if msg != "" {
+				violations = append(violations, &Violation{Rule: p.name, Severity: p.severity, Subject: item.Data.Id, Message: msg})
+			}
+		}
+	}
+	return violations
+}
+
+// policyDaysUntil counts the days from today to a date, or gives None when
+// the date is blank or cannot be read
+func policyDaysUntil(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var date string
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &date); err != nil {
+		return nil, err
+	}
+	t, ok := parseDate(date)
+	if !ok {
+		return starlark.None, nil
+	}
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return starlark.MakeInt(int(day.Sub(today).Hours() / 24)), nil
+}
+
+// policyItem exposes a node or edge to the policy checks. Its attributes are
+// the fields of the graph JSON; edges also have kind, source_node and
+// target_node and nodes have the neighbors() and edges() methods.
+type policyItem struct {
+	item *GraphItem
+	idx  *policyIndex
+}
+
+func (p *policyItem) String() string        { return p.item.Data.Id }
+func (p *policyItem) Type() string          { return strings.TrimSuffix(p.item.Group, "s") }
+func (p *policyItem) Freeze()               {}
+func (p *policyItem) Truth() starlark.Bool  { return starlark.True }
+func (p *policyItem) Hash() (uint32, error) { return starlark.String(p.item.Data.Id).Hash() }
+
+func (p *policyItem) Attr(name string) (starlark.Value, error) {
+	d := p.item.Data
+	switch name {
+	case "id":
+		return starlark.String(d.Id), nil
+	case "source":
+		return starlark.String(d.Source), nil
+	case "target":
+		return starlark.String(d.Target), nil
+	case "group":
+		return starlark.String(p.item.Group), nil
+	}
+
+	if p.item.Group == "edges" {
+		switch name {
+		case "kind":
+			return starlark.String(linkKind(d.Type, p.idx.cfg)), nil
+		case "source_node":
+			return p.node(d.Source), nil
+		case "target_node":
+			return p.node(d.Target), nil
+		}
+	} else {
+		switch name {
+		case "neighbors":
+			return starlark.NewBuiltin("neighbors", p.neighbors).BindReceiver(p), nil
+		case "edges":
+			return starlark.NewBuiltin("edges", p.edges).BindReceiver(p), nil
+		}
+	}
+
+	for _, attr := range dataAttributes {
+		if attr.name != name {
+			continue
+		}
+		return policyValue(reflect.ValueOf(d).Elem().Field(attr.index)), nil
+	}
+	return nil, nil
+}
+
+// policyValue converts a field of the graph JSON to Starlark. Maps become
+// dicts and structs, such as the forecast, structs with their JSON names.
+func policyValue(v reflect.Value) starlark.Value {
+	switch v.Kind() {
+	case reflect.String:
+		return starlark.String(v.String())
+	case reflect.Int, reflect.Int64:
+		return starlark.MakeInt64(v.Int())
+	case reflect.Float32, reflect.Float64:
+		return starlark.Float(v.Float())
+	case reflect.Bool:
+		return starlark.Bool(v.Bool())
+	case reflect.Slice:
+		var list []starlark.Value
+		for i := 0; i < v.Len(); i++ {
+			list = append(list, policyValue(v.Index(i)))
+		}
+		return starlark.NewList(list)
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface()) })
+		dict := starlark.NewDict(len(keys))
+		for _, k := range keys {
+			dict.SetKey(policyValue(k), policyValue(v.MapIndex(k)))
+		}
+		return dict
+	case reflect.Ptr:
+		if v.IsNil() {
+			return starlark.None
+		}
+		return policyValue(v.Elem())
+	case reflect.Struct:
+		fields := make(starlark.StringDict)
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if f.PkgPath != "" || name == "" || name == "-" {
+				continue
+			}
+			fields[name] = policyValue(v.Field(i))
+		}
+		return starlarkstruct.FromStringDict(starlarkstruct.Default, fields)
+	}
+	return starlark.String(fmt.Sprint(v.Interface()))
+}
+
+func (p *policyItem) AttrNames() []string {
+	names := []string{"id", "source", "target", "group"}
+	if p.item.Group == "edges" {
+		names = append(names, "kind", "source_node", "target_node")
+	} else {
+		names = append(names, "neighbors", "edges")
+	}
+	for _, attr := range dataAttributes {
+		names = append(names, attr.name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (p *policyItem) node(id string) starlark.Value {
+	if n, ok := p.idx.graph.m[id]; ok && n.Group == "nodes" {
+		return &policyItem{item: n, idx: p.idx}
+	}
+	return starlark.None
+}
+
+// edgesAt lists the edges of the node matching the link and direction.
+// Direction "in" gives the edges ending at the node, which for dependencies
+// are the issues it depends on, "out" the edges starting at it and anything
+// else both.
+func (p *policyItem) edgesAt(link string, direction string) []*GraphItem {
+	var edges []*GraphItem
+	id := p.item.Data.Id
+	if direction != "out" {
+		edges = append(edges, p.idx.in[id]...)
+	}
+	if direction != "in" {
+		edges = append(edges, p.idx.out[id]...)
+	}
+	var matched []*GraphItem
+	for _, e := range edges {
+		if matchesLink([]string{link}, e.Data.Type, p.idx.cfg) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+// neighbors(link="", direction="both", type="") lists the nodes at the other
+// end of the matching edges
+func (p *policyItem) neighbors(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var link, direction, nodeType string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "link?", &link, "direction?", &direction, "type?", &nodeType); err != nil {
+		return nil, err
+	}
+	var list []starlark.Value
+	seen := make(map[string]bool)
+	for _, e := range p.edgesAt(link, direction) {
+		other := e.Data.Source
+		if other == p.item.Data.Id {
+			other = e.Data.Target
+		}
+		n := p.idx.graph.m[other]
+		if seen[other] || (nodeType != "" && !strings.EqualFold(n.Data.Type, nodeType)) {
+			continue
+		}
+		seen[other] = true
+		list = append(list, &policyItem{item: n, idx: p.idx})
+	}
+	return starlark.NewList(list), nil
+}
+
+// edges(link="", direction="both") lists the matching edges of the node
+func (p *policyItem) edges(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var link, direction string
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "link?", &link, "direction?", &direction); err != nil {
+		return nil, err
+	}
+	var list []starlark.Value
+	for _, e := range p.edgesAt(link, direction) {
+		list = append(list, &policyItem{item: e, idx: p.idx})
+	}
+	return starlark.NewList(list), nil
+}
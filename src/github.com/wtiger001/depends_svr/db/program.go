@@ -0,0 +1,307 @@
+package db
+
+import (
+	"html/template"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// groupProgramIncrements creates a node for each configured program increment,
+// makes it the compound parent of its sprints and rolls the issue dependencies
+// up to program increment level
+func (graph *Graph) groupProgramIncrements(cfg *JiraConfig) {
+	defer timeTrack(time.Now(), "Group Program Increments")
+
+	piOf := graph.assignProgramIncrements(cfg)
+
+	for _, pi := range cfg.ProgramIncrements {
+		n := Node()
+		n.Data.Id = programIncrementID(pi.Name)
+		n.Data.Label = pi.Name
+		n.Data.StartDate = pi.Start
+		n.Data.FinishDate = pi.End
+		n.Data.Type = "pi"
+		graph.add(n)
+	}
+
+	sprints := 0
+	for sprint, pi := range piOf {
+		graph.m[sprint].Data.Parent = pi
+		sprints++
+	}
+
+	// Issues belong to the program increments of the sprints they were planned in
+	_, sprintsOf := graph.sprintMembership()
+	pisOf := make(map[string][]string)
+	for issue, list := range sprintsOf {
+		for _, sprint := range list {
+			if pi, ok := piOf[sprint]; ok {
+				pisOf[issue] = appendUnique(pisOf[issue], pi)
+			}
+		}
+	}
+
+	edges := graph.rollupDependencies(pisOf, "_PI_DEPENDS_", "pi dependency", cfg)
+	for _, e := range edges {
+		if pointsBackwards(graph.m[e.Data.Source], graph.m[e.Data.Target]) {
+			e.Data.Warning = "backwards in time"
+		}
+		graph.add(e)
+	}
+	log.Printf("Grouped %d sprints into %d program increments with %d dependencies\n", sprints, len(cfg.ProgramIncrements), len(edges))
+}
+
+// assignProgramIncrements maps each sprint node to the id of its program
+// increment. A sprint whose name matches a pattern is assigned to that
+// increment, otherwise the sprint start date has to fall in the date range.
+func (graph *Graph) assignProgramIncrements(cfg *JiraConfig) map[string]string {
+	patterns := make([]*regexp.Regexp, len(cfg.ProgramIncrements))
+	for i, pi := range cfg.ProgramIncrements {
+		if pi.Pattern != "" {
+			re, err := regexp.Compile(pi.Pattern)
+			if err != nil {
+				log.Printf("Invalid pattern for program increment %s: %v\n", pi.Name, err)
+				continue
+			}
+			patterns[i] = re
+		}
+	}
+
+	piOf := make(map[string]string)
+	for _, item := range graph.Items {
+		if item.Group != "nodes" || item.Data.Type != "Sprint" {
+			continue
+		}
+		start, hasStart := parseDate(item.Data.StartDate)
+		for i, pi := range cfg.ProgramIncrements {
+			if patterns[i] != nil {
+				if patterns[i].MatchString(item.Data.Label) {
+					piOf[item.Data.Id] = programIncrementID(pi.Name)
+					break
+				}
+				continue
+			}
+			from, ok1 := parseDate(pi.Start)
+			to, ok2 := parseDate(pi.End)
+			if hasStart && ok1 && ok2 && !start.Before(from) && start.Before(to.AddDate(0, 0, 1)) {
+				piOf[item.Data.Id] = programIncrementID(pi.Name)
+				break
+			}
+		}
+	}
+	return piOf
+}
+
+func programIncrementID(name string) string {
+	return validID("PI_" + name)
+}
+
+type programBoard struct {
+	Increments []*boardIncrement
+}
+
+type boardIncrement struct {
+	Name    string
+	Columns []string
+	Rows    []*boardRow
+}
+
+type boardRow struct {
+	Team  string
+	Cells []*boardCell
+}
+
+type boardCell struct {
+	Sprint       string
+	Features     []*Data
+	Dependencies []*boardDependency
+}
+
+type boardDependency struct {
+	Issue string
+	On    string
+	Where string
+	Late  bool
+}
+
+// boardSlot is the place of a sprint on the program board
+type boardSlot struct {
+	pi     string
+	team   string
+	column int
+	date   time.Time
+}
+
+// saveProgramBoard writes a SAFe style program board with one table per
+// program increment. Teams are the rows and the iterations of the increment
+// are the columns. Features are placed in the last sprint they were planned
+// in along with the dependencies they have on other issues.
+func (graph *Graph) saveProgramBoard(file string, cfg *JiraConfig) (err error) {
+	defer timeTrack(time.Now(), "Save Program Board as "+file)
+
+	piOf := graph.assignProgramIncrements(cfg)
+	members, sprintsOf := graph.sprintMembership()
+
+	// Order each team's sprints in an increment to find their iteration
+	bySlot := make(map[string][]string)
+	for sprint, pi := range piOf {
+		key := pi + "|" + graph.m[sprint].Data.Team
+		bySlot[key] = append(bySlot[key], sprint)
+	}
+	slots := make(map[string]*boardSlot)
+	for _, list := range bySlot {
+		sort.Slice(list, func(i, j int) bool {
+			return sprintBefore(graph.m[list[i]], graph.m[list[j]])
+		})
+		for i, sprint := range list {
+			date, _ := parseDate(graph.m[sprint].Data.StartDate)
+			slots[sprint] = &boardSlot{pi: piOf[sprint], team: graph.m[sprint].Data.Team, column: i, date: date}
+		}
+	}
+
+	// Each issue is placed in the last sprint it was planned in
+	placed := make(map[string]*boardSlot)
+	placedIn := make(map[string]string)
+	for issue, list := range sprintsOf {
+		for _, sprint := range list {
+			slot, ok := slots[sprint]
+			if !ok {
+				continue
+			}
+			if cur, ok := placed[issue]; !ok || slot.date.After(cur.date) {
+				placed[issue] = slot
+				placedIn[issue] = sprint
+			}
+		}
+	}
+
+	// Collect the providers of each dependent issue
+	providers := make(map[string][]string)
+	for _, item := range graph.Items {
+		if item.Group == "edges" && isDependencyLink(item.Data.Type, cfg) && graph.isIssue(item.Data.Source) && graph.isIssue(item.Data.Target) {
+			providers[item.Data.Target] = appendUnique(providers[item.Data.Target], item.Data.Source)
+		}
+	}
+
+	board := new(programBoard)
+	for _, pi := range cfg.ProgramIncrements {
+		inc := &boardIncrement{Name: pi.Name}
+		id := programIncrementID(pi.Name)
+
+		columns := 0
+		var teams []string
+		for _, slot := range slots {
+			if slot.pi != id {
+				continue
+			}
+			if slot.column+1 > columns {
+				columns = slot.column + 1
+			}
+			teams = appendUnique(teams, slot.team)
+		}
+		sort.Strings(teams)
+		for i := 0; i < columns; i++ {
+			inc.Columns = append(inc.Columns, "Iteration "+strconv.Itoa(i+1))
+		}
+
+		for _, team := range teams {
+			row := &boardRow{Team: team, Cells: make([]*boardCell, columns)}
+			for i := range row.Cells {
+				row.Cells[i] = new(boardCell)
+			}
+			for sprint, slot := range slots {
+				if slot.pi != id || slot.team != team {
+					continue
+				}
+				cell := row.Cells[slot.column]
+				cell.Sprint = graph.m[sprint].Data.Label
+				for _, issue := range members[sprint] {
+					if placedIn[issue] != sprint {
+						continue
+					}
+					if graph.m[issue].Data.Type == "feature" {
+						cell.Features = append(cell.Features, graph.m[issue].Data)
+					}
+					for _, p := range providers[issue] {
+						dep := &boardDependency{Issue: issue, On: p, Where: "unplanned"}
+						if ps, ok := placed[p]; ok {
+							dep.Where = ps.team + ", " + graph.m[placedIn[p]].Data.Label
+							dep.Late = ps.date.After(slot.date)
+						}
+						cell.Dependencies = append(cell.Dependencies, dep)
+					}
+				}
+				sort.Slice(cell.Features, func(i, j int) bool { return cell.Features[i].Id < cell.Features[j].Id })
+				sort.Slice(cell.Dependencies, func(i, j int) bool {
+					return cell.Dependencies[i].Issue+cell.Dependencies[i].On < cell.Dependencies[j].Issue+cell.Dependencies[j].On
+				})
+			}
+			inc.Rows = append(inc.Rows, row)
+		}
+		board.Increments = append(board.Increments, inc)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return programBoardTemplate.Execute(f, board)
+}
+
+// sprintBefore orders sprints by start date and then by name
+func sprintBefore(a *GraphItem, b *GraphItem) bool {
+	startA, ok1 := parseDate(a.Data.StartDate)
+	startB, ok2 := parseDate(b.Data.StartDate)
+	if ok1 && ok2 && !startA.Equal(startB) {
+		return startA.Before(startB)
+	}
+	if ok1 != ok2 {
+		return ok1
+	}
+	return a.Data.Label < b.Data.Label
+}
+
+var programBoardTemplate = template.Must(template.New("board").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Program Board</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #999; padding: 4px; vertical-align: top; min-width: 160px; }
+th { background: #eee; }
+.sprint { color: #666; font-style: italic; }
+.feature { background: #cfe2ff; margin: 2px 0; padding: 2px; }
+.dependency { background: #fff3cd; margin: 2px 0; padding: 2px; }
+.late { background: #f8d7da; }
+</style>
+</head>
+<body>
+{{range .Increments}}
+<h2>{{.Name}}</h2>
+<table>
+<tr><th>Team</th>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}
+<tr>
+<th>{{.Team}}</th>
+{{range .Cells}}
+<td>
+<div class="sprint">{{.Sprint}}</div>
+{{range .Features}}<div class="feature">{{.Id}} {{.Label}}</div>{{end}}
+{{range .Dependencies}}<div class="dependency{{if .Late}} late{{end}}">{{.Issue}} depends on {{.On}} ({{.Where}})</div>{{end}}
+</td>
+{{end}}
+</tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
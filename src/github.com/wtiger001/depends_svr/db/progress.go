@@ -0,0 +1,170 @@
+package db
+
+import (
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// rollupProgress works out how far along each capability and thread is from
+// the features and requirements under it. Progress is the share of them that
+// are done and, when story points are mapped, points progress is the share of
+// their points. Cancelled work is left out of both. The derived status is
+// "done", "at risk", "in progress" or "not started".
+func (graph *Graph) rollupProgress(cfg *JiraConfig) {
+	defer timeTrack(time.Now(), "Rollup Progress")
+
+	adjacent, providers := graph.issueLinks(cfg)
+	_, sprintsOf := graph.sprintMembership()
+
+	counts := make(map[string]int)
+	for _, item := range graph.Items {
+		d := item.Data
+		if item.Group != "nodes" || (d.Type != "capability" && d.Type != "thread") {
+			continue
+		}
+		work := graph.workOf(d, adjacent, providers)
+
+		var total, done int
+		var points, pointsDone float64
+		started, blocked := false, false
+		var lastSprint []string
+		for _, id := range work {
+			w := graph.m[id].Data
+			state := issueState(w, cfg)
+			if state == "cancelled" {
+				continue
+			}
+			total++
+			points += w.Points
+			if state == "closed" {
+				done++
+				pointsDone += w.Points
+				started = true
+				continue
+			}
+			status := strings.ToLower(w.Status)
+			if strings.Contains(status, "progress") || strings.Contains(status, "review") || strings.Contains(status, "test") {
+				started = true
+			}
+			if strings.Contains(status, "blocked") {
+				blocked = true
+			}
+			lastSprint = append(lastSprint, sprintsOf[id]...)
+		}
+
+		d.Progress, d.PointsProgress = 0, 0
+		if total > 0 {
+			d.Progress = percent(done, total)
+		}
+		if cfg.PointsField != "" && points > 0 {
+			d.PointsProgress = math.Round(1000*pointsDone/points) / 10
+		}
+
+		switch {
+		case issueState(d, cfg) != "open" || (total > 0 && done == total):
+			d.DerivedStatus = "done"
+		case blocked || graph.pastDue(d, lastSprint):
+			d.DerivedStatus = "at risk"
+		case started:
+			d.DerivedStatus = "in progress"
+		default:
+			d.DerivedStatus = "not started"
+		}
+		counts[d.DerivedStatus]++
+	}
+
+	var states []string
+	for s := range counts {
+		states = append(states, s)
+	}
+	sort.Strings(states)
+	for _, s := range states {
+		log.Printf("\t%-12s: %d\n", s, counts[s])
+	}
+}
+
+// issueLinks indexes the links between issues: the issues joined by parent
+// or trace links in either direction and the issues each issue depends on
+func (graph *Graph) issueLinks(cfg *JiraConfig) (adjacent map[string][]string, providers map[string][]string) {
+	adjacent = make(map[string][]string)
+	providers = make(map[string][]string)
+	for _, item := range graph.Items {
+		src, ok1 := graph.m[item.Data.Source]
+		tgt, ok2 := graph.m[item.Data.Target]
+		if item.Group != "edges" || !ok1 || !ok2 || src == tgt || !graph.isIssue(src.Data.Id) || !graph.isIssue(tgt.Data.Id) {
+			continue
+		}
+		switch linkKind(item.Data.Type, cfg) {
+		case "parent", "traces":
+			adjacent[src.Data.Id] = appendUnique(adjacent[src.Data.Id], tgt.Data.Id)
+			adjacent[tgt.Data.Id] = appendUnique(adjacent[tgt.Data.Id], src.Data.Id)
+		case "depends":
+			// The source is the issue that is depended on
+			providers[tgt.Data.Id] = appendUnique(providers[tgt.Data.Id], src.Data.Id)
+		}
+	}
+	return adjacent, providers
+}
+
+// workOf lists the features and requirements a capability or thread is made
+// of. A capability has the features linked to it and the requirements traced
+// to it or to those features. A thread has the features and requirements it
+// is linked to or depends on, and the work of its capabilities.
+func (graph *Graph) workOf(d *Data, adjacent map[string][]string, providers map[string][]string) []string {
+	var work []string
+	addWork := func(id string) {
+		n := graph.m[id].Data
+		if n.Type != "feature" && n.Type != "requirement" {
+			return
+		}
+		work = appendUnique(work, id)
+		if n.Type == "feature" {
+			for _, r := range adjacent[id] {
+				if graph.m[r].Data.Type == "requirement" {
+					work = appendUnique(work, r)
+				}
+			}
+		}
+	}
+
+	if d.Type == "capability" {
+		for _, id := range adjacent[d.Id] {
+			addWork(id)
+		}
+		sort.Strings(work)
+		return work
+	}
+
+	for _, id := range append(append([]string{}, adjacent[d.Id]...), providers[d.Id]...) {
+		if graph.m[id].Data.Type == "capability" {
+			for _, w := range graph.workOf(graph.m[id].Data, adjacent, providers) {
+				work = appendUnique(work, w)
+			}
+			continue
+		}
+		addWork(id)
+	}
+	sort.Strings(work)
+	return work
+}
+
+// pastDue checks whether the item's finish date has passed or the sprints
+// with its open work finish after it
+func (graph *Graph) pastDue(d *Data, sprints []string) bool {
+	due, ok := parseDate(d.FinishDate)
+	if !ok {
+		return false
+	}
+	if time.Now().After(due) {
+		return true
+	}
+	last, ok := graph.lastRelease(sprints)
+	return ok && last.After(due)
+}
+
+func percent(n int, total int) float64 {
+	return math.Round(1000*float64(n)/float64(total)) / 10
+}
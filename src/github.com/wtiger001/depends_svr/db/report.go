@@ -0,0 +1,264 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:generate go run gen_cytoscape.go
+
+// cytoscapeJS is the Cytoscape.js build the report's viewer runs on. It is set
+// by cytoscape_js.go, which go generate writes.
+var cytoscapeJS string
+
+type reportCount struct {
+	Type  string
+	Count int
+}
+
+type htmlReport struct {
+	Run       *Run
+	Generated string
+	Nodes     int
+	Edges     int
+	NodeTypes []reportCount
+	EdgeTypes []reportCount
+	Good      int
+	Findings  []*Violation
+	Graph     template.JS
+	Viewer    template.JS
+	Picture   template.HTML
+}
+
+// saveHTMLReport writes a single file report with the graph summary, the
+// validation findings and an interactive viewer. The graph is embedded so the
+// file works offline once it is emailed. A build without Cytoscape.js shows a
+// static SVG of the graph instead of the viewer.
+func (graph *Graph) saveHTMLReport(file string, cfg *JiraConfig) (err error) {
+	defer timeTrack(time.Now(), "Save HTML Report as "+file)
+
+	// The JSON encoder escapes < and > so the graph cannot close the script
+	graphJSON, err := json.Marshal(graph.Items)
+	if err != nil {
+		return err
+	}
+
+	s := graph.summary()
+	r := &htmlReport{
+		Run:       graph.Run,
+		Generated: time.Now().Format(time.RFC1123),
+		Nodes:     s.NodeCount,
+		Edges:     s.EdgeCount,
+		NodeTypes: reportCounts(s.Nodes),
+		EdgeTypes: reportCounts(s.Edges),
+		Graph:     template.JS(graphJSON),
+		// The library must not end the script it is embedded in
+		Viewer: template.JS(strings.Replace(cytoscapeJS, "</script", "<\\/script", -1)),
+	}
+	r.Good, _ = graph.missingEnds()
+	r.Findings = graph.Findings
+
+	if cytoscapeJS == "" {
+		log.Printf("Cytoscape.js is not bundled in this build, the report shows a static SVG; run go generate in the db package to bundle it\n")
+		// The SVG lays out a copy so the graph keeps its own positions
+		c, err := graph.clone()
+		if err != nil {
+			return err
+		}
+		var svg bytes.Buffer
+		if err = c.writeSVG(&svg, cfg); err != nil {
+			return err
+		}
+		picture := svg.String()
+		if i := strings.Index(picture, "<svg"); i >= 0 {
+			picture = picture[i:]
+		}
+		r.Picture = template.HTML(picture)
+	}
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = htmlReportTemplate.Execute(f, r)
+	if err == nil {
+		log.Printf("Wrote HTML report %s\n", file)
+	}
+	return err
+}
+
+// reportCounts orders the histogram by count, largest first
+func reportCounts(m map[string]int) []reportCount {
+	var list []reportCount
+	for k, v := range m {
+		list = append(list, reportCount{Type: k, Count: v})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Type < list[j].Type
+	})
+	return list
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Depends Report</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; margin: 0; display: flex; height: 100vh; }
+#side { width: 320px; overflow-y: auto; padding: 8px; border-right: 1px solid #999; box-sizing: border-box; }
+#main { flex: 1; position: relative; }
+#view { position: absolute; top: 0; bottom: 0; left: 0; right: 0; }
+#view.static { overflow: auto; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 8px; }
+td, th { border: 1px solid #ccc; padding: 2px 4px; text-align: left; }
+td.n { text-align: right; }
+h2 { font-size: 14px; margin: 12px 0 4px; }
+#search { width: 100%; box-sizing: border-box; }
+#details { white-space: pre-wrap; }
+.error td { color: #a00; }
+.warning td { color: #a60; }
+label { display: block; }
+</style>
+</head>
+<body>
+<div id="side">
+<h1>Depends Report</h1>
+<div>Generated {{.Generated}}</div>
+{{with .Run}}<div>Extracted from {{.JiraURL}} {{range .Projects}}{{.}} {{end}}at {{.Finished}}</div>{{end}}
+
+<h2>Search</h2>
+<input id="search" placeholder="Key or summary">
+
+<h2>Selection</h2>
+<div id="details">Click a node to highlight its neighborhood</div>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Nodes</th><th class="n">{{.Nodes}}</th><th>Show</th></tr>
+{{range .NodeTypes}}<tr><td>{{.Type}}</td><td class="n">{{.Count}}</td><td><input type="checkbox" class="filter" value="{{.Type}}" checked></td></tr>
+{{end}}
+<tr><th>Edges</th><th class="n">{{.Edges}}</th><th></th></tr>
+{{range .EdgeTypes}}<tr><td>{{.Type}}</td><td class="n">{{.Count}}</td><td></td></tr>
+{{end}}
+</table>
+
+<h2>Validation</h2>
+<div>Good edges: {{.Good}}, findings: {{len .Findings}}</div>
+<table>
+{{range .Findings}}<tr class="{{.Severity}}"><td>{{.Subject}}</td><td>{{.Rule}}</td><td>{{.Message}}</td></tr>
+{{end}}
+</table>
+</div>
+{{if .Viewer}}<div id="main"><div id="view"></div></div>
+<script>{{.Viewer}}</script>
+<script>
+var items = {{.Graph}};
+(function() {
+  var view = document.getElementById("view");
+
+  var ids = {}, placed = true, elements = [];
+  items.forEach(function(item) {
+    if (item.group === "nodes") {
+      ids[item.data.id] = true;
+      placed = placed && !!item.position;
+    }
+  });
+  items.forEach(function(item) {
+    // Edges with a missing end are in the validation findings instead
+    if (item.group === "edges" && !(ids[item.data.source] && ids[item.data.target])) return;
+    if (item.data.parent && !ids[item.data.parent]) {
+      item = { group: item.group, data: Object.assign({}, item.data, { parent: undefined }), position: item.position };
+    }
+    elements.push(item);
+  });
+
+  var colors = {}, palette = ["#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f", "#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac"];
+  function color(type) {
+    if (!(type in colors)) colors[type] = palette[Object.keys(colors).length % palette.length];
+    return colors[type];
+  }
+
+  // Positions from the -layout option are used as they are
+  var cy = cytoscape({
+    container: view,
+    elements: elements,
+    layout: { name: placed ? "preset" : "cose", animate: false },
+    style: [
+      { selector: "node", style: { "background-color": function(n) { return color(n.data("type")); }, width: 10, height: 10 } },
+      { selector: "edge", style: { width: 1, "line-color": "#bbb", "target-arrow-color": "#bbb", "target-arrow-shape": "triangle", "arrow-scale": 0.5, "curve-style": "bezier" } },
+      { selector: "node.lit", style: { label: "data(id)", "font-size": 8 } },
+      { selector: "edge.lit", style: { "line-color": "#666", "target-arrow-color": "#666" } },
+      { selector: "node:selected", style: { width: 16, height: 16, "border-width": 2, "border-color": "#000" } },
+      { selector: ".faded", style: { opacity: 0.15 } },
+      { selector: ".hidden", style: { display: "none" } }
+    ]
+  });
+
+  function highlight(eles) {
+    cy.elements().removeClass("lit").addClass("faded");
+    eles.removeClass("faded").addClass("lit");
+  }
+  function clear() {
+    cy.elements().removeClass("faded lit");
+    details(null);
+  }
+  function details(n) {
+    var el = document.getElementById("details");
+    if (!n) { el.textContent = "Click a node to highlight its neighborhood"; return; }
+    var d = n.data(), lines = [];
+    Object.keys(d).forEach(function(k) { lines.push(k + ": " + d[k]); });
+    lines.push("neighbors: " + n.neighborhood("node").map(function(m) { return m.id(); }).join(", "));
+    el.textContent = lines.join("\n");
+  }
+  function focus(n) {
+    cy.nodes().unselect();
+    n.select();
+    highlight(n.closedNeighborhood());
+    details(n);
+  }
+
+  cy.on("tap", "node", function(ev) { focus(ev.target); });
+  cy.on("tap", function(ev) { if (ev.target === cy) clear(); });
+
+  document.getElementById("search").addEventListener("input", function(ev) {
+    var q = ev.target.value.toLowerCase();
+    if (!q) { clear(); return; }
+    var hits = cy.nodes().filter(function(n) {
+      return (n.id() + " " + (n.data("label") || "")).toLowerCase().indexOf(q) >= 0;
+    });
+    if (hits.length === 1) {
+      focus(hits[0]);
+      cy.center(hits[0]);
+    } else {
+      highlight(hits);
+      details(null);
+    }
+  });
+  Array.prototype.forEach.call(document.querySelectorAll(".filter"), function(box) {
+    box.addEventListener("change", function() {
+      cy.nodes().filter(function(n) { return n.data("type") === box.value; }).toggleClass("hidden", !box.checked);
+    });
+  });
+})();
+</script>
+{{else}}<div id="main"><div id="view" class="static">{{.Picture}}</div></div>
+<script>
+// Without the viewer the search and type filters have nothing to drive
+Array.prototype.forEach.call(document.querySelectorAll("#search, .filter"), function(input) { input.disabled = true; });
+document.getElementById("details").textContent = "This build has no interactive viewer, the graph is a static picture";
+</script>
+{{end}}</body>
+</html>
+`))
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveHTMLReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		library string
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "with the viewer",
+			library: "window.cytoscape = function() {}; // </script>",
+			want:    []string{"window.cytoscape = function() {}; // <\\/script>", "var items = [", `"PIR-1"`},
+			notWant: []string{"<svg", "static picture"},
+		},
+		{
+			name:    "without the viewer",
+			library: "",
+			want:    []string{`<div id="view" class="static"><svg`, "static picture", "PIR-1"},
+			notWant: []string{"var items", "<?xml"},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(library string) { cytoscapeJS = library }(cytoscapeJS)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cytoscapeJS = tt.library
+			graph := testGraph(testNode("PIR-1", "feature", "", ""), testNode("PIR-2", "requirement", "", ""), testEdge("PIR-1", "PIR-2", "depends on"))
+			file := filepath.Join(dir, "report.html")
+			if err := graph.saveHTMLReport(file, testConfig()); err != nil {
+				t.Fatal(err)
+			}
+			raw, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(raw), want) {
+					t.Errorf("report is missing %s", want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(raw), notWant) {
+					t.Errorf("report has %s", notWant)
+				}
+			}
+			if graph.m["PIR-1"].Position != nil {
+				t.Errorf("the report laid out the graph itself")
+			}
+		})
+	}
+}
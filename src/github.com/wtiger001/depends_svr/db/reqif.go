@@ -0,0 +1,222 @@
+package db
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+type reqIF struct {
+	XMLName xml.Name         `xml:"REQ-IF"`
+	Xmlns   string           `xml:"xmlns,attr,omitempty"`
+	Header  reqIFHeader      `xml:"THE-HEADER>REQ-IF-HEADER"`
+	Content reqIFCoreContent `xml:"CORE-CONTENT>REQ-IF-CONTENT"`
+}
+
+type reqIFHeader struct {
+	Identifier   string `xml:"IDENTIFIER,attr"`
+	CreationTime string `xml:"CREATION-TIME"`
+	ToolID       string `xml:"REQ-IF-TOOL-ID"`
+	Version      string `xml:"REQ-IF-VERSION"`
+	SourceToolID string `xml:"SOURCE-TOOL-ID"`
+	Title        string `xml:"TITLE"`
+}
+
+type reqIFCoreContent struct {
+	Datatypes      []reqIFDatatype      `xml:"DATATYPES>DATATYPE-DEFINITION-STRING"`
+	ObjectTypes    []reqIFSpecType      `xml:"SPEC-TYPES>SPEC-OBJECT-TYPE"`
+	RelationTypes  []reqIFSpecType      `xml:"SPEC-TYPES>SPEC-RELATION-TYPE"`
+	SpecTypes      []reqIFSpecType      `xml:"SPEC-TYPES>SPECIFICATION-TYPE"`
+	Objects        []reqIFSpecObject    `xml:"SPEC-OBJECTS>SPEC-OBJECT"`
+	Relations      []reqIFSpecRelation  `xml:"SPEC-RELATIONS>SPEC-RELATION"`
+	Specifications []reqIFSpecification `xml:"SPECIFICATIONS>SPECIFICATION"`
+}
+
+type reqIFIdentifiable struct {
+	Identifier string `xml:"IDENTIFIER,attr"`
+	LastChange string `xml:"LAST-CHANGE,attr"`
+	LongName   string `xml:"LONG-NAME,attr,omitempty"`
+}
+
+type reqIFDatatype struct {
+	reqIFIdentifiable
+	MaxLength int `xml:"MAX-LENGTH,attr"`
+}
+
+type reqIFSpecType struct {
+	reqIFIdentifiable
+	Attributes []reqIFAttributeDefinition `xml:"SPEC-ATTRIBUTES>ATTRIBUTE-DEFINITION-STRING"`
+}
+
+type reqIFAttributeDefinition struct {
+	reqIFIdentifiable
+	Type string `xml:"TYPE>DATATYPE-DEFINITION-STRING-REF"`
+}
+
+type reqIFSpecObject struct {
+	reqIFIdentifiable
+	Values []reqIFValue `xml:"VALUES>ATTRIBUTE-VALUE-STRING"`
+	Type   string       `xml:"TYPE>SPEC-OBJECT-TYPE-REF"`
+}
+
+type reqIFValue struct {
+	Value      string `xml:"THE-VALUE,attr"`
+	Definition string `xml:"DEFINITION>ATTRIBUTE-DEFINITION-STRING-REF"`
+}
+
+type reqIFSpecRelation struct {
+	reqIFIdentifiable
+	Source string `xml:"SOURCE>SPEC-OBJECT-REF"`
+	Target string `xml:"TARGET>SPEC-OBJECT-REF"`
+	Type   string `xml:"TYPE>SPEC-RELATION-TYPE-REF"`
+}
+
+type reqIFSpecification struct {
+	reqIFIdentifiable
+	Type     string               `xml:"TYPE>SPECIFICATION-TYPE-REF"`
+	Children []reqIFSpecHierarchy `xml:"CHILDREN>SPEC-HIERARCHY"`
+}
+
+type reqIFSpecHierarchy struct {
+	reqIFIdentifiable
+	Children []reqIFSpecHierarchy `xml:"CHILDREN>SPEC-HIERARCHY"`
+	Object   string               `xml:"OBJECT>SPEC-OBJECT-REF"`
+}
+
+// reqIFTypes are the node types exported as SPEC-OBJECTs
+var reqIFTypes = []string{"capability", "feature", "requirement"}
+
+// writeReqIF writes the capabilities, features and requirements as a ReqIF
+// 1.2 document. Trace and parent links between them become SPEC-RELATIONs and
+// a hierarchy specification follows the parent links from the top down.
+func (graph *Graph) writeReqIF(w io.Writer, cfg *JiraConfig) error {
+	now := time.Now().Format(time.RFC3339)
+	id := func(identifier string, name string) reqIFIdentifiable {
+		return reqIFIdentifiable{Identifier: identifier, LastChange: now, LongName: name}
+	}
+
+	doc := reqIF{Xmlns: "http://www.omg.org/spec/ReqIF/20110401/reqif.xsd"}
+	doc.Header = reqIFHeader{
+		Identifier:   "depends-" + time.Now().Format("20060102150405"),
+		CreationTime: now,
+		ToolID:       "depends_svr",
+		Version:      "1.0",
+		SourceToolID: "JIRA",
+		Title:        "Depends requirements export",
+	}
+	c := &doc.Content
+	c.Datatypes = []reqIFDatatype{{reqIFIdentifiable: id("DT_STRING", "String"), MaxLength: 32000}}
+
+	// One object type per node type, each with all the Data attributes
+	for _, t := range reqIFTypes {
+		st := reqIFSpecType{reqIFIdentifiable: id(reqIFID("SOT", t), t)}
+		for _, attr := range dataAttributes {
+			st.Attributes = append(st.Attributes, reqIFAttributeDefinition{reqIFIdentifiable: id(reqIFID("AD", t+"_"+attr.name), attr.name), Type: "DT_STRING"})
+		}
+		c.ObjectTypes = append(c.ObjectTypes, st)
+	}
+
+	included := make(map[string]bool)
+	for _, item := range graph.Items {
+		if item.Group != "nodes" || !isReqIFType(item.Data.Type) {
+			continue
+		}
+		included[item.Data.Id] = true
+		o := reqIFSpecObject{reqIFIdentifiable: id(reqIFID("SO", item.Data.Id), item.Data.Id), Type: reqIFID("SOT", item.Data.Type)}
+		for _, attr := range dataAttributes {
+			if v, ok := attr.value(item.Data); ok {
+				o.Values = append(o.Values, reqIFValue{Value: v, Definition: reqIFID("AD", item.Data.Type+"_"+attr.name)})
+			}
+		}
+		c.Objects = append(c.Objects, o)
+	}
+
+	// Trace and parent links between the exported objects
+	relationTypes := make(map[string]bool)
+	parents := make(map[string][]string)
+	hasParent := make(map[string]bool)
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !included[item.Data.Source] || !included[item.Data.Target] {
+			continue
+		}
+		kind := linkKind(item.Data.Type, cfg)
+		if kind != "traces" && kind != "parent" {
+			continue
+		}
+		linkType := graph.canonicalLinkType(item, cfg)
+		if !relationTypes[linkType] {
+			relationTypes[linkType] = true
+			c.RelationTypes = append(c.RelationTypes, reqIFSpecType{reqIFIdentifiable: id(reqIFID("SRT", linkType), linkType)})
+		}
+		c.Relations = append(c.Relations, reqIFSpecRelation{
+			reqIFIdentifiable: id(reqIFID("SR", item.Data.Id), ""),
+			Source:            reqIFID("SO", item.Data.Source),
+			Target:            reqIFID("SO", item.Data.Target),
+			Type:              reqIFID("SRT", linkType),
+		})
+
+		// Parent edges point from the child to the parent
+		if kind == "parent" {
+			parents[item.Data.Target] = appendUnique(parents[item.Data.Target], item.Data.Source)
+			hasParent[item.Data.Source] = true
+		}
+	}
+
+	// The hierarchy starts from every object without a parent
+	c.SpecTypes = []reqIFSpecType{{reqIFIdentifiable: id("ST_HIERARCHY", "Hierarchy")}}
+	spec := reqIFSpecification{reqIFIdentifiable: id("SPEC_HIERARCHY", "Parent / Child Hierarchy"), Type: "ST_HIERARCHY"}
+	var roots []string
+	for key := range included {
+		if !hasParent[key] {
+			roots = append(roots, key)
+		}
+	}
+	sort.Strings(roots)
+	counter := 0
+	var build func(key string, path map[string]bool) reqIFSpecHierarchy
+	build = func(key string, path map[string]bool) reqIFSpecHierarchy {
+		counter++
+		h := reqIFSpecHierarchy{reqIFIdentifiable: id(fmt.Sprintf("SH_%d", counter), ""), Object: reqIFID("SO", key)}
+		path[key] = true
+		children := parents[key]
+		sort.Strings(children)
+		for _, child := range children {
+			if !path[child] {
+				h.Children = append(h.Children, build(child, path))
+			}
+		}
+		delete(path, key)
+		return h
+	}
+	for _, root := range roots {
+		spec.Children = append(spec.Children, build(root, make(map[string]bool)))
+	}
+	c.Specifications = []reqIFSpecification{spec}
+
+	io.WriteString(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
+
+func isReqIFType(nodeType string) bool {
+	for _, t := range reqIFTypes {
+		if t == nodeType {
+			return true
+		}
+	}
+	return false
+}
+
+// reqIFID makes an identifier that is a valid xsd:ID
+func reqIFID(prefix string, name string) string {
+	return prefix + "_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '.' {
+			return r
+		}
+		return '_'
+	}, name)
+}
@@ -0,0 +1,187 @@
+package db
+
+import (
+	"encoding/xml"
+	"html"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// reqIFInput is the subset of a ReqIF document needed to import it. Unlike
+// the export it accepts every attribute value and definition type.
+type reqIFInput struct {
+	ObjectTypes []reqIFInputType    `xml:"CORE-CONTENT>REQ-IF-CONTENT>SPEC-TYPES>SPEC-OBJECT-TYPE"`
+	Relations   []reqIFNamed        `xml:"CORE-CONTENT>REQ-IF-CONTENT>SPEC-TYPES>SPEC-RELATION-TYPE"`
+	EnumValues  []reqIFNamed        `xml:"CORE-CONTENT>REQ-IF-CONTENT>DATATYPES>DATATYPE-DEFINITION-ENUMERATION>SPECIFIED-VALUES>ENUM-VALUE"`
+	Objects     []reqIFInputObject  `xml:"CORE-CONTENT>REQ-IF-CONTENT>SPEC-OBJECTS>SPEC-OBJECT"`
+	Links       []reqIFSpecRelation `xml:"CORE-CONTENT>REQ-IF-CONTENT>SPEC-RELATIONS>SPEC-RELATION"`
+	Specs       []reqIFInputSpec    `xml:"CORE-CONTENT>REQ-IF-CONTENT>SPECIFICATIONS>SPECIFICATION"`
+}
+
+type reqIFNamed struct {
+	Identifier string `xml:"IDENTIFIER,attr"`
+	LongName   string `xml:"LONG-NAME,attr"`
+}
+
+type reqIFInputType struct {
+	reqIFNamed
+	Definitions struct {
+		List []reqIFNamed `xml:",any"`
+	} `xml:"SPEC-ATTRIBUTES"`
+}
+
+type reqIFInputObject struct {
+	reqIFNamed
+	Values struct {
+		List []reqIFInputValue `xml:",any"`
+	} `xml:"VALUES"`
+	Type string `xml:"TYPE>SPEC-OBJECT-TYPE-REF"`
+}
+
+type reqIFInputValue struct {
+	TheValue   string          `xml:"THE-VALUE,attr"`
+	XHTML      reqIFInnerXML   `xml:"THE-VALUE"`
+	Definition reqIFInnerXML   `xml:"DEFINITION"`
+	EnumRefs   []reqIFInnerXML `xml:"VALUES>ENUM-VALUE-REF"`
+}
+
+type reqIFInnerXML struct {
+	Inner string `xml:",innerxml"`
+}
+
+type reqIFInputSpec struct {
+	Children []reqIFInputHierarchy `xml:"CHILDREN>SPEC-HIERARCHY"`
+}
+
+type reqIFInputHierarchy struct {
+	Object   string                `xml:"OBJECT>SPEC-OBJECT-REF"`
+	Children []reqIFInputHierarchy `xml:"CHILDREN>SPEC-HIERARCHY"`
+}
+
+var xmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// text strips the markup from XHTML values and references
+func (x reqIFInnerXML) text() string {
+	return strings.TrimSpace(html.UnescapeString(xmlTags.ReplaceAllString(x.Inner, " ")))
+}
+
+// importReqIF reads the SPEC-OBJECTs of a ReqIF file (e.g. a DOORS export) as
+// nodes, the SPEC-RELATIONs as edges and the specification hierarchies as
+// parent / child links. Attributes named after the Data fields are copied
+// and the common DOORS attributes are used for the id, label and description.
+func (graph *Graph) importReqIF(file string, cfg *JiraConfig) (err error) {
+	defer timeTrack(time.Now(), "Import ReqIF "+file)
+
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	doc := new(reqIFInput)
+	if err = xml.Unmarshal(raw, doc); err != nil {
+		return err
+	}
+
+	names := make(map[string]string)
+	for _, list := range [][]reqIFNamed{doc.Relations, doc.EnumValues} {
+		for _, n := range list {
+			names[n.Identifier] = n.LongName
+		}
+	}
+	for _, t := range doc.ObjectTypes {
+		names[t.Identifier] = t.LongName
+		for _, n := range t.Definitions.List {
+			names[n.Identifier] = n.LongName
+		}
+	}
+
+	ids := make(map[string]string)
+	for _, o := range doc.Objects {
+		values := make(map[string]string)
+		for _, v := range o.Values.List {
+			name := strings.ToLower(names[v.Definition.text()])
+			switch {
+			case v.TheValue != "":
+				values[name] = v.TheValue
+			case len(v.EnumRefs) > 0:
+				var list []string
+				for _, ref := range v.EnumRefs {
+					list = append(list, names[ref.text()])
+				}
+				values[name] = strings.Join(list, ";")
+			default:
+				values[name] = v.XHTML.text()
+			}
+		}
+
+		n := Node()
+		n.Data.Id = firstOf(values["id"], values["reqif.foreignid"], o.LongName, o.Identifier)
+		n.Data.Label = firstOf(values["label"], values["reqif.name"], values["reqif.chaptername"], values["object heading"])
+		n.Data.Description = firstOf(values["description"], values["reqif.text"], values["object text"])
+		if n.Data.Label == "" {
+			n.Data.Label = strings.SplitN(n.Data.Description, "\n", 2)[0]
+		}
+		n.Data.Type = getNodeType(firstOf(values["type"], names[o.Type]), cfg)
+		if !isIssueType(n.Data.Type) {
+			n.Data.Type = "requirement"
+		}
+		for name, v := range values {
+			switch name {
+			case "id", "label", "description", "type":
+			default:
+				setDataField(n.Data, name, v)
+			}
+		}
+
+		ids[o.Identifier] = validID(n.Data.Id)
+		graph.merge(n)
+	}
+
+	linked := make(map[string]bool)
+	for _, r := range doc.Links {
+		e := Edge()
+		e.Data.Id = r.Identifier
+		e.Data.Source = ids[r.Source]
+		e.Data.Target = ids[r.Target]
+		e.Data.Type = firstOf(names[r.Type], r.Type)
+		if e.Data.Source != "" && e.Data.Target != "" {
+			linked[e.Data.Source+">"+e.Data.Target] = true
+			graph.merge(e)
+		}
+	}
+
+	// Children point to their parents, the same as the JIRA parent links. A
+	// hierarchy that repeats a relation does not add a second edge.
+	var walk func(parent string, children []reqIFInputHierarchy)
+	walk = func(parent string, children []reqIFInputHierarchy) {
+		for _, h := range children {
+			child := ids[h.Object]
+			if parent != "" && child != "" && child != parent && !linked[child+">"+parent] {
+				e := Edge()
+				e.Data.Id = child + "_PARENT_" + parent
+				e.Data.Source = child
+				e.Data.Target = parent
+				e.Data.Type = cfg.ChildLink
+				graph.merge(e)
+			}
+			walk(child, h.Children)
+		}
+	}
+	for _, spec := range doc.Specs {
+		walk("", spec.Children)
+	}
+
+	log.Printf("Imported %d objects and %d relations from %s\n", len(doc.Objects), len(doc.Links), file)
+	return nil
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package db
+
+import (
+	"sort"
+	"time"
+)
+
+// scheduleTask is a dated node for the calendar and plan exports. The id is
+// unique among the tasks, node is the graph node the task stands for.
+type scheduleTask struct {
+	id           string
+	node         string
+	name         string
+	section      string
+	start        time.Time
+	finish       time.Time
+	milestone    bool
+	predecessors []string
+}
+
+// scheduleSections orders the groups of tasks in the plans
+var scheduleSections = []string{"Program Increments", "Sprints", "Releases", "Thread Deadlines", "Issues"}
+
+// schedule collects the sprints, program increments, releases and thread
+// deadlines with their dates. Issues span from the start of their first sprint
+// to the end of their last and follow the issues they depend on.
+func (graph *Graph) schedule(cfg *JiraConfig) []*scheduleTask {
+	var tasks []*scheduleTask
+	byID := make(map[string]*scheduleTask)
+	add := func(t *scheduleTask) {
+		tasks = append(tasks, t)
+		byID[t.id] = t
+	}
+
+	for _, item := range graph.Items {
+		if item.Group != "nodes" {
+			continue
+		}
+		d := item.Data
+		start, hasStart := parseDate(d.StartDate)
+		finish, hasFinish := parseDate(d.FinishDate)
+		t := &scheduleTask{id: d.Id, node: d.Id, name: firstOf(d.Label, d.Id), start: start, finish: finish}
+		switch d.Type {
+		case "Sprint", "pi":
+			if !hasStart || !hasFinish {
+				continue
+			}
+			t.section = "Sprints"
+			if d.Type == "pi" {
+				t.section = "Program Increments"
+			}
+		case "version", "thread":
+			if !hasFinish {
+				continue
+			}
+			t.section = "Releases"
+			if d.Type == "thread" {
+				// A thread in a sprint also has a span under the issues
+				t.id = d.Id + "_DUE"
+				t.section = "Thread Deadlines"
+				t.name = d.Id + " " + d.Label
+			}
+			t.start = finish
+			t.milestone = true
+		default:
+			continue
+		}
+		add(t)
+	}
+
+	// Sprint membership gives the issues their spans
+	_, sprintsOf := graph.sprintMembership()
+	for issue, sprints := range sprintsOf {
+		n, ok := graph.m[issue]
+		if !ok {
+			continue
+		}
+		var t *scheduleTask
+		for _, sprint := range sprints {
+			s, ok := byID[sprint]
+			if !ok || s.section != "Sprints" {
+				continue
+			}
+			if t == nil {
+				t = &scheduleTask{id: issue, node: issue, name: issue + " " + n.Data.Label, section: "Issues", start: s.start, finish: s.finish}
+			}
+			if s.start.Before(t.start) {
+				t.start = s.start
+			}
+			if s.finish.After(t.finish) {
+				t.finish = s.finish
+			}
+		}
+		if t != nil {
+			add(t)
+		}
+	}
+
+	task := func(id string) *scheduleTask {
+		if t, ok := byID[id]; ok {
+			return t
+		}
+		return byID[id+"_DUE"]
+	}
+	for _, item := range graph.Items {
+		// Sprint membership edges share the dependency link name
+		if item.Group != "edges" || !isDependencyLink(item.Data.Type, cfg) || !graph.isIssue(item.Data.Source) || !graph.isIssue(item.Data.Target) {
+			continue
+		}
+		// The source is the issue that is depended on. A thread that is in no
+		// sprint stands in with its deadline.
+		t, p := task(item.Data.Target), task(item.Data.Source)
+		if t != nil && p != nil && item.Data.Source != item.Data.Target {
+			t.predecessors = appendUnique(t.predecessors, p.id)
+		}
+	}
+
+	section := make(map[string]int)
+	for i, s := range scheduleSections {
+		section[s] = i
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		a, b := tasks[i], tasks[j]
+		if a.section != b.section {
+			return section[a.section] < section[b.section]
+		}
+		if !a.start.Equal(b.start) {
+			return a.start.Before(b.start)
+		}
+		return a.id < b.id
+	})
+	for _, t := range tasks {
+		sort.Strings(t.predecessors)
+	}
+	return tasks
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestSchedule(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []*GraphItem
+		want  []string
+	}{
+		{
+			name: "sprints without dates are left out",
+			items: []*GraphItem{
+				testNode("1", "Sprint", "2017-01-02", "2017-01-16"),
+				testNode("2", "Sprint", "", ""),
+			},
+			want: []string{"1 Sprints 2017-01-02 2017-01-16 []"},
+		},
+		{
+			name: "issues span their sprints and follow their dependencies",
+			items: []*GraphItem{
+				testNode("1", "Sprint", "2017-01-02", "2017-01-16"),
+				testNode("2", "Sprint", "2017-01-16", "2017-01-30"),
+				testNode("PIR-1", "feature", "", ""),
+				testNode("PIR-2", "requirement", "", ""),
+				testSprint("1", "PIR-1"),
+				testSprint("2", "PIR-1"),
+				testSprint("2", "PIR-2"),
+				testEdge("PIR-1", "PIR-2", "depends on"),
+			},
+			want: []string{
+				"1 Sprints 2017-01-02 2017-01-16 []",
+				"2 Sprints 2017-01-16 2017-01-30 []",
+				"PIR-1 Issues 2017-01-02 2017-01-30 []",
+				"PIR-2 Issues 2017-01-16 2017-01-30 [PIR-1]",
+			},
+		},
+		{
+			name: "a thread in a sprint has a deadline and a span",
+			items: []*GraphItem{
+				testNode("1", "Sprint", "2017-01-02", "2017-01-16"),
+				testNode("PIR-1", "feature", "", ""),
+				testNode("PIR-5", "thread", "", "2017-02-01"),
+				testSprint("1", "PIR-1"),
+				testSprint("1", "PIR-5"),
+				testEdge("PIR-1", "PIR-5", "depends on"),
+			},
+			want: []string{
+				"1 Sprints 2017-01-02 2017-01-16 []",
+				"PIR-5_DUE Thread Deadlines 2017-02-01 2017-02-01 []",
+				"PIR-1 Issues 2017-01-02 2017-01-16 []",
+				"PIR-5 Issues 2017-01-02 2017-01-16 [PIR-1]",
+			},
+		},
+		{
+			name: "a thread in no sprint follows its dependencies with its deadline",
+			items: []*GraphItem{
+				testNode("1", "Sprint", "2017-01-02", "2017-01-16"),
+				testNode("PIR-1", "feature", "", ""),
+				testNode("PIR-5", "thread", "", "2017-02-01"),
+				testSprint("1", "PIR-1"),
+				testEdge("PIR-1", "PIR-5", "depends on"),
+			},
+			want: []string{
+				"1 Sprints 2017-01-02 2017-01-16 []",
+				"PIR-5_DUE Thread Deadlines 2017-02-01 2017-02-01 [PIR-1]",
+				"PIR-1 Issues 2017-01-02 2017-01-16 []",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			ids := make(map[string]bool)
+			for _, task := range testGraph(tt.items...).schedule(testConfig()) {
+				if ids[task.id] {
+					t.Errorf("duplicate task id %s", task.id)
+				}
+				ids[task.id] = true
+				got = append(got, fmt.Sprintf("%s %s %s %s %v", task.id, task.section,
+					task.start.Format("2006-01-02"), task.finish.Format("2006-01-02"), task.predecessors))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("schedule() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
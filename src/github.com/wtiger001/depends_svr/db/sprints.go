@@ -0,0 +1,116 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// sprintMembership maps each sprint to the issues planned in it and each issue
+// to the sprints it was planned in. Only the direct membership edges created by
+// aggregateSprintIssue count, not the edges to the issues they link to.
+func (graph *Graph) sprintMembership() (members map[string][]string, sprintsOf map[string][]string) {
+	members = make(map[string][]string)
+	sprintsOf = make(map[string][]string)
+	for _, item := range graph.Items {
+		if item.Group != "edges" || item.Data.Id != item.Data.Target+"_SPRINT_"+item.Data.Source {
+			continue
+		}
+		if sprint, ok := graph.m[item.Data.Source]; !ok || sprint.Data.Type != "Sprint" {
+			continue
+		}
+		members[item.Data.Source] = appendUnique(members[item.Data.Source], item.Data.Target)
+		sprintsOf[item.Data.Target] = appendUnique(sprintsOf[item.Data.Target], item.Data.Source)
+	}
+	return members, sprintsOf
+}
+
+// deriveSprintDependencies builds weighted sprint to sprint edges from the
+// dependency links between the issues planned in each sprint. An edge whose
+// providing sprint finishes after the dependent sprint points backwards in time
+// and is flagged.
+func (graph *Graph) deriveSprintDependencies(cfg *JiraConfig) {
+	defer timeTrack(time.Now(), "Derive Sprint Dependencies")
+
+	_, sprintsOf := graph.sprintMembership()
+	edges := graph.rollupDependencies(sprintsOf, "_SPRINT_DEPENDS_", "sprint dependency", cfg)
+
+	backwards := 0
+	crossTeam := 0
+	for _, e := range edges {
+		src := graph.m[e.Data.Source]
+		tgt := graph.m[e.Data.Target]
+		if src.Data.Team != tgt.Data.Team {
+			e.Data.Description += fmt.Sprintf(" from %s to %s", src.Data.Team, tgt.Data.Team)
+			crossTeam++
+		}
+		if pointsBackwards(src, tgt) {
+			e.Data.Warning = "backwards in time"
+			log.Printf("\tBackwards sprint dependency: %s (%s) -> %s (%s)\n", src.Data.Label, src.Data.FinishDate, tgt.Data.Label, tgt.Data.FinishDate)
+			backwards++
+		}
+		graph.add(e)
+	}
+	log.Printf("Derived %d sprint dependencies (%d across teams, %d backwards in time)\n", len(edges), crossTeam, backwards)
+}
+
+// rollupDependencies lifts the dependency links between issues onto the groups
+// (sprints, program increments) that the issues belong to. Issue edges point
+// from the issue that is depended on to the issue that depends on it, so the
+// derived edges point from the providing group to the dependent group. The
+// edges are returned in a stable order and are not added to the graph.
+func (graph *Graph) rollupDependencies(groupsOf map[string][]string, infix string, edgeType string, cfg *JiraConfig) []*GraphItem {
+	edges := make(map[string]*GraphItem)
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !isDependencyLink(item.Data.Type, cfg) {
+			continue
+		}
+		if !graph.isIssue(item.Data.Source) || !graph.isIssue(item.Data.Target) {
+			continue
+		}
+		for _, src := range groupsOf[item.Data.Source] {
+			for _, tgt := range groupsOf[item.Data.Target] {
+				if src == tgt {
+					continue
+				}
+				id := src + infix + tgt
+				e, ok := edges[id]
+				if !ok {
+					e = Edge()
+					e.Data.Id = id
+					e.Data.Source = src
+					e.Data.Target = tgt
+					e.Data.Type = edgeType
+					edges[id] = e
+				}
+				e.Data.Weight++
+				e.Data.Issues = appendUnique(e.Data.Issues, item.Data.Source)
+				e.Data.Issues = appendUnique(e.Data.Issues, item.Data.Target)
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(edges))
+	for id := range edges {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	result := make([]*GraphItem, 0, len(ids))
+	for _, id := range ids {
+		e := edges[id]
+		sort.Strings(e.Data.Issues)
+		e.Data.Description = fmt.Sprintf("%d issue dependencies", e.Data.Weight)
+		result = append(result, e)
+	}
+	return result
+}
+
+// pointsBackwards checks whether the providing sprint finishes after the
+// sprint that depends on it
+func pointsBackwards(provider *GraphItem, dependent *GraphItem) bool {
+	pf, ok1 := parseDate(provider.Data.FinishDate)
+	df, ok2 := parseDate(dependent.Data.FinishDate)
+	return ok1 && ok2 && pf.After(df)
+}
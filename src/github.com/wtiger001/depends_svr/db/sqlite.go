@@ -0,0 +1,180 @@
+//go:build sqlite
+// +build sqlite
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	// Registers the sqlite3 driver, which needs cgo, so the SQLite output is
+	// only built with the sqlite tag
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteSchema keeps every run in the same database. The current views only
+// show the latest run.
+var sqliteSchema = []string{
+	`CREATE TABLE IF NOT EXISTS runs (
+		id       INTEGER PRIMARY KEY AUTOINCREMENT,
+		started  TEXT,
+		finished TEXT,
+		jira_url TEXT,
+		projects TEXT,
+		nodes    INTEGER,
+		edges    INTEGER
+	)`,
+	`CREATE TABLE IF NOT EXISTS nodes (
+		run_id      INTEGER NOT NULL REFERENCES runs(id),
+		id          TEXT NOT NULL,
+		type        TEXT,
+		label       TEXT,
+		status      TEXT,
+		component   TEXT,
+		version     TEXT,
+		team        TEXT,
+		parent      TEXT,
+		start_date  TEXT,
+		finish_date TEXT,
+		description TEXT,
+		data        TEXT,
+		PRIMARY KEY (run_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS edges (
+		run_id      INTEGER NOT NULL REFERENCES runs(id),
+		id          TEXT NOT NULL,
+		source      TEXT,
+		target      TEXT,
+		type        TEXT,
+		weight      INTEGER,
+		warning     TEXT,
+		description TEXT,
+		data        TEXT,
+		PRIMARY KEY (run_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS sprints (
+		run_id      INTEGER NOT NULL REFERENCES runs(id),
+		id          TEXT NOT NULL,
+		name        TEXT,
+		state       TEXT,
+		team        TEXT,
+		start_date  TEXT,
+		finish_date TEXT,
+		PRIMARY KEY (run_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS components (
+		run_id      INTEGER NOT NULL REFERENCES runs(id),
+		id          TEXT NOT NULL,
+		name        TEXT,
+		description TEXT,
+		PRIMARY KEY (run_id, id)
+	)`,
+	`CREATE TABLE IF NOT EXISTS labels (
+		run_id INTEGER NOT NULL REFERENCES runs(id),
+		issue  TEXT NOT NULL,
+		label  TEXT NOT NULL,
+		PRIMARY KEY (run_id, issue, label)
+	)`,
+	`CREATE INDEX IF NOT EXISTS nodes_type ON nodes (run_id, type)`,
+	`CREATE INDEX IF NOT EXISTS edges_source ON edges (run_id, source)`,
+	`CREATE INDEX IF NOT EXISTS edges_target ON edges (run_id, target)`,
+	`CREATE INDEX IF NOT EXISTS edges_type ON edges (run_id, type)`,
+	`CREATE INDEX IF NOT EXISTS labels_label ON labels (run_id, label)`,
+	`CREATE VIEW IF NOT EXISTS current_nodes AS SELECT * FROM nodes WHERE run_id = (SELECT MAX(id) FROM runs)`,
+	`CREATE VIEW IF NOT EXISTS current_edges AS SELECT * FROM edges WHERE run_id = (SELECT MAX(id) FROM runs)`,
+}
+
+// saveSQLite adds the graph to a SQLite database as a new run
+func (graph *Graph) saveSQLite(file string) (err error) {
+	defer timeTrack(time.Now(), "Save SQLite "+file)
+
+	conn, err := sql.Open("sqlite3", file)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, stmt := range sqliteSchema {
+		if _, err = conn.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	run := graph.Run
+	if run == nil {
+		now := time.Now().Format(time.RFC3339)
+		run = &Run{Started: now, Finished: now}
+	}
+	nodes, edges := 0, 0
+	for _, item := range graph.Items {
+		if item.Group == "nodes" {
+			nodes++
+		} else {
+			edges++
+		}
+	}
+	res, err := tx.Exec(`INSERT INTO runs (started, finished, jira_url, projects, nodes, edges) VALUES (?, ?, ?, ?, ?, ?)`,
+		run.Started, run.Finished, run.JiraURL, strings.Join(run.Projects, ","), nodes, edges)
+	if err != nil {
+		return err
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for _, item := range graph.Items {
+		d := item.Data
+		data, _ := json.Marshal(d)
+		if item.Group == "edges" {
+			_, err = tx.Exec(`INSERT INTO edges VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				runID, d.Id, d.Source, d.Target, d.Type, d.Weight, d.Warning, d.Description, string(data))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		_, err = tx.Exec(`INSERT INTO nodes VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, d.Id, d.Type, d.Label, d.Status, d.Component, d.Version, d.Team, d.Parent, d.StartDate, d.FinishDate, d.Description, string(data))
+		if err != nil {
+			return err
+		}
+		switch d.Type {
+		case "Sprint":
+			_, err = tx.Exec(`INSERT INTO sprints VALUES (?, ?, ?, ?, ?, ?, ?)`,
+				runID, d.Id, d.Label, d.Status, d.Team, d.StartDate, d.FinishDate)
+		case "component":
+			_, err = tx.Exec(`INSERT INTO components VALUES (?, ?, ?, ?)`,
+				runID, d.Id, d.Label, d.Description)
+		}
+		if err != nil {
+			return err
+		}
+		for _, label := range d.Labels {
+			_, err = tx.Exec(`INSERT OR IGNORE INTO labels VALUES (?, ?, ?)`, runID, d.Id, label)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = tx.Commit()
+	if err == nil {
+		log.Printf("Added run %d with %d nodes and %d edges to %s\n", runID, nodes, edges, file)
+	}
+	return err
+}
@@ -0,0 +1,12 @@
+//go:build !sqlite
+// +build !sqlite
+
+package db
+
+import "fmt"
+
+// saveSQLite is not available without the sqlite build tag, which brings in
+// the cgo SQLite driver
+func (graph *Graph) saveSQLite(file string) error {
+	return fmt.Errorf("SQLite output needs a build with -tags sqlite")
+}
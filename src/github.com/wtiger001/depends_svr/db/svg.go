@@ -0,0 +1,227 @@
+package db
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	svgNodeWidth  = 72.0
+	svgNodeHeight = 30.0
+	svgMargin     = 60.0
+)
+
+// svgEdgeStyles gives each kind of link a color, dash pattern and arrowhead
+var svgEdgeStyles = map[string]struct {
+	color string
+	dash  string
+}{
+	"depends": {"#a61c00", ""},
+	"traces":  {"#38761d", "6,3"},
+	"parent":  {"#1155cc", ""},
+	"release": {"#b45f06", "2,2"},
+	"other":   {"#999999", ""},
+}
+
+// svgStatusColor outlines the nodes by how far along they are
+func svgStatusColor(status string) string {
+	s := strings.ToLower(status)
+	switch {
+	case s == "":
+		return "#666666"
+	case strings.Contains(s, "done"), strings.Contains(s, "closed"), strings.Contains(s, "resolved"), strings.Contains(s, "released"):
+		return "#38761d"
+	case strings.Contains(s, "progress"), strings.Contains(s, "active"), strings.Contains(s, "review"):
+		return "#1155cc"
+	case strings.Contains(s, "blocked"), strings.Contains(s, "overdue"), strings.Contains(s, "risk"):
+		return "#cc0000"
+	}
+	return "#666666"
+}
+
+// saveSVG writes the graph as an SVG image
+func (graph *Graph) saveSVG(file string, cfg *JiraConfig) (err error) {
+	defer timeTrack(time.Now(), "Save SVG as "+file)
+
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return graph.writeSVG(f, cfg)
+}
+
+// writeSVG draws the nodes at their layout positions, shaped and filled by
+// type and outlined by status, with the edges styled by link kind. Graphs
+// without positions get the configured layout first, or the layered layout
+// when none is set or it is unknown.
+func (graph *Graph) writeSVG(w io.Writer, cfg *JiraConfig) error {
+	var nodes []*GraphItem
+	for _, item := range graph.Items {
+		if item.Group == "nodes" {
+			nodes = append(nodes, item)
+		}
+	}
+	unplaced := func() bool {
+		for _, n := range nodes {
+			if n.Position == nil {
+				return true
+			}
+		}
+		return false
+	}
+	if unplaced() {
+		graph.layout(cfg)
+	}
+	// An unknown layout leaves the nodes where they were
+	if unplaced() {
+		graph.layeredLayout()
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Data.Id < nodes[j].Data.Id })
+
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for _, n := range nodes {
+		minX, maxX = math.Min(minX, n.Position.X), math.Max(maxX, n.Position.X)
+		minY, maxY = math.Min(minY, n.Position.Y), math.Max(maxY, n.Position.Y)
+	}
+	if len(nodes) == 0 {
+		minX, minY, maxX, maxY = 0, 0, 0, 0
+	}
+	x := func(n *GraphItem) float64 { return n.Position.X - minX + svgMargin }
+	y := func(n *GraphItem) float64 { return n.Position.Y - minY + svgMargin }
+
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n")
+	fmt.Fprintf(out, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%.0f\" height=\"%.0f\" font-family=\"sans-serif\" font-size=\"8\">\n",
+		maxX-minX+2*svgMargin, maxY-minY+2*svgMargin)
+
+	fmt.Fprintf(out, "<defs>\n")
+	for _, kind := range []string{"depends", "traces", "parent", "release", "other"} {
+		style := svgEdgeStyles[kind]
+		path := "M0,0 L10,5 L0,10 z"
+		fill := style.color
+		switch kind {
+		case "traces":
+			path, fill = "M0,0 L10,5 L0,10", "none"
+		case "parent":
+			path = "M0,5 L5,0 L10,5 L5,10 z"
+		}
+		fmt.Fprintf(out, "<marker id=\"arrow-%s\" viewBox=\"0 0 10 10\" refX=\"10\" refY=\"5\" markerWidth=\"8\" markerHeight=\"8\" orient=\"auto\">"+
+			"<path d=\"%s\" fill=\"%s\" stroke=\"%s\"/></marker>\n", kind, path, fill, style.color)
+	}
+	fmt.Fprintf(out, "</defs>\n")
+
+	// Edges go underneath and stop at the edge of the target box
+	fmt.Fprintf(out, "<g id=\"edges\" fill=\"none\">\n")
+	for _, item := range graph.Items {
+		src, ok1 := graph.m[item.Data.Source]
+		tgt, ok2 := graph.m[item.Data.Target]
+		if item.Group != "edges" || !ok1 || !ok2 || src == tgt || src.Position == nil || tgt.Position == nil {
+			continue
+		}
+		kind := linkKind(item.Data.Type, cfg)
+		style := svgEdgeStyles[kind]
+		x1, y1, x2, y2 := x(src), y(src), x(tgt), y(tgt)
+		dx, dy := x2-x1, y2-y1
+		if dx == 0 && dy == 0 {
+			continue
+		}
+		t := math.Min(math.Abs(svgNodeWidth/2/dx), math.Abs(svgNodeHeight/2/dy))
+		x2, y2 = x2-dx*t, y2-dy*t
+		fmt.Fprintf(out, "<line x1=\"%.1f\" y1=\"%.1f\" x2=\"%.1f\" y2=\"%.1f\" stroke=\"%s\"", x1, y1, x2, y2, style.color)
+		if style.dash != "" {
+			fmt.Fprintf(out, " stroke-dasharray=\"%s\"", style.dash)
+		}
+		fmt.Fprintf(out, " marker-end=\"url(#arrow-%s)\"><title>%s</title></line>\n", kind, svgText(item.Data.Type))
+	}
+	fmt.Fprintf(out, "</g>\n")
+
+	fmt.Fprintf(out, "<g id=\"nodes\">\n")
+	for _, n := range nodes {
+		cx, cy := x(n), y(n)
+		fill, ok := mermaidColors[n.Data.Type]
+		if !ok {
+			fill = "#ffffff"
+		}
+		fmt.Fprintf(out, "<g><title>%s</title>\n", svgText(n.Data.Id+" "+n.Data.Label))
+		fmt.Fprintf(out, "%s fill=\"%s\" stroke=\"%s\" stroke-width=\"1.5\"/>\n", svgShape(n.Data.Type, cx, cy), fill, svgStatusColor(firstOf(n.Data.DerivedStatus, n.Data.Status)))
+		lines := svgLines(firstOf(n.Data.Label, n.Data.Id), 16, 2)
+		for i, line := range lines {
+			ty := cy + 3 + (float64(i)-float64(len(lines)-1)/2)*9
+			fmt.Fprintf(out, "<text x=\"%.1f\" y=\"%.1f\" text-anchor=\"middle\">%s</text>\n", cx, ty, svgText(line))
+		}
+		fmt.Fprintf(out, "</g>\n")
+	}
+	fmt.Fprintf(out, "</g>\n")
+	fmt.Fprintf(out, "</svg>\n")
+
+	return out.Flush()
+}
+
+// svgShape opens the element for a node centered on cx, cy
+func svgShape(nodeType string, cx float64, cy float64) string {
+	w, h := svgNodeWidth/2, svgNodeHeight/2
+	points := func(pts ...float64) string {
+		var list []string
+		for i := 0; i < len(pts); i += 2 {
+			list = append(list, fmt.Sprintf("%.1f,%.1f", cx+pts[i], cy+pts[i+1]))
+		}
+		return "<polygon points=\"" + strings.Join(list, " ") + "\""
+	}
+	switch nodeType {
+	case "capability":
+		return points(-w, 0, -w+8, -h, w-8, -h, w, 0, w-8, h, -w+8, h)
+	case "requirement":
+		return points(-w, -h, w-8, -h, w, -h+8, w, h, -w, h)
+	case "component":
+		return points(-w, 0, 0, -h, w, 0, 0, h)
+	case "Sprint":
+		return points(-w+6, -h, w, -h, w-6, h, -w, h)
+	case "thread":
+		return fmt.Sprintf("<ellipse cx=\"%.1f\" cy=\"%.1f\" rx=\"%.1f\" ry=\"%.1f\"", cx, cy, w, h)
+	case "feature":
+		return fmt.Sprintf("<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\" rx=\"6\"", cx-w, cy-h, svgNodeWidth, svgNodeHeight)
+	}
+	return fmt.Sprintf("<rect x=\"%.1f\" y=\"%.1f\" width=\"%.1f\" height=\"%.1f\"", cx-w, cy-h, svgNodeWidth, svgNodeHeight)
+}
+
+// svgLines wraps the label onto a few short lines, cutting off the rest
+func svgLines(label string, width int, max int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(label) {
+		if line != "" && len(line)+1+len(word) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	if len(lines) > max {
+		lines = lines[:max]
+		lines[max-1] += "..."
+	}
+	for i, l := range lines {
+		if r := []rune(l); len(r) > width+3 {
+			lines[i] = string(r[:width]) + "..."
+		}
+	}
+	return lines
+}
+
+func svgText(s string) string {
+	return html.EscapeString(s)
+}
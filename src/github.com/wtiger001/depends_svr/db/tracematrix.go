@@ -0,0 +1,352 @@
+package db
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TraceMatrix has the requirements as rows and the features and capabilities
+// they trace to as columns. A cell is "X" when a trace or parent link joins
+// the two directly and "~" when they are joined through one feature or
+// capability in between.
+type TraceMatrix struct {
+	Requirements []*Data
+	Columns      []*Data
+	Cells        map[string]map[string]string
+	Coverage     []*TraceCoverage
+	Untraced     []*Data
+	Unrequired   []*Data
+}
+
+// TraceCoverage counts the items of one type that trace to the other end
+type TraceCoverage struct {
+	Name    string
+	Covered int
+	Total   int
+}
+
+// Percent is the share of the items that are covered
+func (c *TraceCoverage) Percent() string {
+	if c.Total == 0 {
+		return "0.0%"
+	}
+	return strconv.FormatFloat(100*float64(c.Covered)/float64(c.Total), 'f', 1, 64) + "%"
+}
+
+// Cell gives the mark for a requirement and a column
+func (m *TraceMatrix) Cell(requirement string, column string) string {
+	return m.Cells[requirement][column]
+}
+
+// TraceMatrixReport writes the traceability matrix as HTML, XLSX or both
+func TraceMatrixReport(graph *Graph, htmlFile string, xlsxFile string, cfg *JiraConfig) (err error) {
+	defer timeTrack(time.Now(), "Trace Matrix")
+
+	m := graph.traceMatrix(cfg)
+	if htmlFile != "" {
+		if err = m.saveHTML(htmlFile); err != nil {
+			return err
+		}
+	}
+	if xlsxFile != "" {
+		if err = m.saveXLSX(xlsxFile); err != nil {
+			return err
+		}
+	}
+	for _, c := range m.Coverage {
+		log.Printf("%s: %d of %d (%s)\n", c.Name, c.Covered, c.Total, c.Percent())
+	}
+	return nil
+}
+
+// traceMatrix follows the trace and parent links between requirements,
+// features and capabilities in either direction, since JIRA records them
+// from whichever end the link was made
+func (graph *Graph) traceMatrix(cfg *JiraConfig) *TraceMatrix {
+	adjacent := make(map[string][]string)
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !graph.isTraceable(item.Data.Source) || !graph.isTraceable(item.Data.Target) {
+			continue
+		}
+		kind := linkKind(item.Data.Type, cfg)
+		if kind != "traces" && kind != "parent" {
+			continue
+		}
+		adjacent[item.Data.Source] = appendUnique(adjacent[item.Data.Source], item.Data.Target)
+		adjacent[item.Data.Target] = appendUnique(adjacent[item.Data.Target], item.Data.Source)
+	}
+
+	m := &TraceMatrix{Cells: make(map[string]map[string]string)}
+	reached := make(map[string]bool)
+	direct := make(map[string]bool)
+	for _, item := range graph.Items {
+		if item.Group != "nodes" || item.Data.Type != "requirement" {
+			continue
+		}
+		req := item.Data.Id
+		m.Requirements = append(m.Requirements, item.Data)
+		cells := make(map[string]string)
+		for _, n := range adjacent[req] {
+			if graph.m[n].Data.Type == "requirement" {
+				continue
+			}
+			cells[n] = "X"
+			for _, nn := range adjacent[n] {
+				if _, ok := cells[nn]; !ok && graph.m[nn].Data.Type != "requirement" {
+					cells[nn] = "~"
+				}
+			}
+		}
+		for n, mark := range cells {
+			reached[n] = true
+			direct[n] = direct[n] || mark == "X"
+		}
+		m.Cells[req] = cells
+		if len(cells) == 0 {
+			m.Untraced = append(m.Untraced, item.Data)
+		}
+	}
+
+	// Only the features and capabilities that a requirement reaches get a
+	// column. A feature only counts as having requirements when one traces to
+	// it directly, the features without one are listed instead.
+	counts := map[string]*TraceCoverage{
+		"feature":    {Name: "Features with requirements"},
+		"capability": {Name: "Capabilities with requirements"},
+	}
+	for _, item := range graph.Items {
+		c, ok := counts[item.Data.Type]
+		if item.Group != "nodes" || !ok {
+			continue
+		}
+		c.Total++
+		if reached[item.Data.Id] {
+			m.Columns = append(m.Columns, item.Data)
+		}
+		switch {
+		case item.Data.Type == "capability" && reached[item.Data.Id]:
+			c.Covered++
+		case item.Data.Type == "feature" && direct[item.Data.Id]:
+			c.Covered++
+		case item.Data.Type == "feature":
+			m.Unrequired = append(m.Unrequired, item.Data)
+		}
+	}
+
+	byID := func(list []*Data) {
+		sort.Slice(list, func(i, j int) bool { return list[i].Id < list[j].Id })
+	}
+	byID(m.Requirements)
+	byID(m.Untraced)
+	byID(m.Unrequired)
+	sort.Slice(m.Columns, func(i, j int) bool {
+		if m.Columns[i].Type != m.Columns[j].Type {
+			return m.Columns[i].Type == "capability"
+		}
+		return m.Columns[i].Id < m.Columns[j].Id
+	})
+
+	m.Coverage = []*TraceCoverage{
+		{Name: "Requirements traced", Covered: len(m.Requirements) - len(m.Untraced), Total: len(m.Requirements)},
+		counts["feature"],
+		counts["capability"],
+	}
+	return m
+}
+
+func (graph *Graph) isTraceable(id string) bool {
+	n, ok := graph.m[id]
+	return ok && n.Group == "nodes" && isReqIFType(n.Data.Type)
+}
+
+func (m *TraceMatrix) saveHTML(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return traceMatrixTemplate.Execute(f, m)
+}
+
+// saveXLSX writes a workbook with the matrix, coverage and untraced sheets.
+// The sheets use inline strings so no shared string table is needed.
+func (m *TraceMatrix) saveXLSX(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	matrix := [][]string{{"Requirement", "Summary"}}
+	for _, c := range m.Columns {
+		matrix[0] = append(matrix[0], c.Id+" ("+c.Type+")")
+	}
+	for _, r := range m.Requirements {
+		row := []string{r.Id, r.Label}
+		for _, c := range m.Columns {
+			row = append(row, m.Cell(r.Id, c.Id))
+		}
+		matrix = append(matrix, row)
+	}
+
+	coverage := [][]string{{"Measure", "Covered", "Total", "Percent"}}
+	for _, c := range m.Coverage {
+		coverage = append(coverage, []string{c.Name, strconv.Itoa(c.Covered), strconv.Itoa(c.Total), c.Percent()})
+	}
+
+	gaps := [][]string{{"Gap", "Key", "Summary"}}
+	for _, d := range m.Untraced {
+		gaps = append(gaps, []string{"Untraced requirement", d.Id, d.Label})
+	}
+	for _, d := range m.Unrequired {
+		gaps = append(gaps, []string{"Feature without requirements", d.Id, d.Label})
+	}
+
+	sheets := []struct {
+		name string
+		rows [][]string
+	}{{"Matrix", matrix}, {"Coverage", coverage}, {"Gaps", gaps}}
+
+	z := zip.NewWriter(f)
+	files := map[string]string{
+		"[Content_Types].xml":        xlsxContentTypes(len(sheets)),
+		"_rels/.rels":                xlsxRootRels,
+		"xl/_rels/workbook.xml.rels": xlsxWorkbookRels(len(sheets)),
+	}
+	workbook := xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`
+	for i, s := range sheets {
+		workbook += fmt.Sprintf(`<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, s.name, i+1, i+1)
+	}
+	files["xl/workbook.xml"] = workbook + `</sheets></workbook>`
+
+	names := []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels"}
+	for _, name := range names {
+		w, err := z.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err = io.WriteString(w, files[name]); err != nil {
+			return err
+		}
+	}
+	for i, s := range sheets {
+		w, err := z.Create(fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1))
+		if err != nil {
+			return err
+		}
+		if err = writeXLSXSheet(w, s.rows); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+func writeXLSXSheet(w io.Writer, rows [][]string) error {
+	io.WriteString(w, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for r, row := range rows {
+		fmt.Fprintf(w, `<row r="%d">`, r+1)
+		for c, v := range row {
+			if v == "" {
+				continue
+			}
+			if _, err := strconv.Atoi(v); err == nil {
+				fmt.Fprintf(w, `<c r="%s%d"><v>%s</v></c>`, xlsxColumn(c), r+1, v)
+				continue
+			}
+			fmt.Fprintf(w, `<c r="%s%d" t="inlineStr"><is><t>`, xlsxColumn(c), r+1)
+			if err := xml.EscapeText(w, []byte(v)); err != nil {
+				return err
+			}
+			io.WriteString(w, `</t></is></c>`)
+		}
+		io.WriteString(w, `</row>`)
+	}
+	_, err := io.WriteString(w, `</sheetData></worksheet>`)
+	return err
+}
+
+// xlsxColumn converts a zero based column number to its letters (A, Z, AA)
+func xlsxColumn(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+func xlsxContentTypes(sheets int) string {
+	s := xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`
+	for i := 1; i <= sheets; i++ {
+		s += fmt.Sprintf(`<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	return s + `</Types>`
+}
+
+func xlsxWorkbookRels(sheets int) string {
+	s := xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`
+	for i := 1; i <= sheets; i++ {
+		s += fmt.Sprintf(`<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	return s + `</Relationships>`
+}
+
+var traceMatrixTemplate = template.Must(template.New("matrix").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Traceability Matrix</title>
+<style>
+body { font-family: sans-serif; font-size: 12px; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+th, td { border: 1px solid #999; padding: 4px; }
+th { background: #eee; }
+.matrix th.column { writing-mode: vertical-rl; transform: rotate(180deg); white-space: nowrap; }
+.matrix td.mark { text-align: center; }
+.direct { background: #cfe2ff; }
+.indirect { background: #e7f1ff; color: #666; }
+</style>
+</head>
+<body>
+<h1>Traceability Matrix</h1>
+<h2>Coverage</h2>
+<table>
+<tr><th>Measure</th><th>Covered</th><th>Total</th><th>Percent</th></tr>
+{{range .Coverage}}<tr><td>{{.Name}}</td><td>{{.Covered}}</td><td>{{.Total}}</td><td>{{.Percent}}</td></tr>
+{{end}}
+</table>
+<h2>Matrix</h2>
+<p>X: traced directly, ~: traced through a feature or capability</p>
+<table class="matrix">
+<tr><th>Requirement</th>{{range .Columns}}<th class="column" title="{{.Label}}">{{.Id}} ({{.Type}})</th>{{end}}</tr>
+{{$m := .}}{{range $r := .Requirements}}<tr><th title="{{$r.Label}}">{{$r.Id}}</th>{{range $c := $m.Columns}}{{$v := $m.Cell $r.Id $c.Id}}<td class="mark{{if eq $v "X"}} direct{{else if eq $v "~"}} indirect{{end}}">{{$v}}</td>{{end}}</tr>
+{{end}}
+</table>
+<h2>Untraced Requirements</h2>
+<ul>
+{{range .Untraced}}<li>{{.Id}} {{.Label}}</li>
+{{else}}<li>None</li>
+{{end}}
+</ul>
+<h2>Features Without Requirements</h2>
+<ul>
+{{range .Unrequired}}<li>{{.Id}} {{.Label}}</li>
+{{else}}<li>None</li>
+{{end}}
+</ul>
+</body>
+</html>
+`))
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Metamodel describes the relationships the graph is allowed to have. Links
+// limits what the nodes of a type may link to and Rules sets how many links
+// of a kind each node of a type must have.
+type Metamodel struct {
+	Links []AllowedLink   `json:"links"`
+	Rules []MetamodelRule `json:"rules"`
+}
+
+// AllowedLink lets a node of type From link to the To types, in either
+// direction, with the listed links. A link is a link kind (depends, traces,
+// parent, release, other) or a link name; no links allows any. Once a type
+// has an entry every link it has must match one of its entries.
+type AllowedLink struct {
+	From  string   `json:"from"`
+	Links []string `json:"links"`
+	To    []string `json:"to"`
+}
+
+// MetamodelRule counts the links of a node of Type to nodes of the To types.
+// Direction "out" only counts the edges starting at the node, "in" the edges
+// ending at it and anything else both. Max 0 means there is no upper limit.
+type MetamodelRule struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Link      string   `json:"link"`
+	To        []string `json:"to"`
+	Direction string   `json:"direction"`
+	Min       int      `json:"min"`
+	Max       int      `json:"max"`
+	Severity  string   `json:"severity"`
+}
+
+// Violation is a problem found while validating the graph. The subject is the
+// key of the issue or the id of the item it is about.
+type Violation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Subject  string `json:"subject"`
+	Message  string `json:"message"`
+}
+
+// defaultMetamodel is used when the configuration has none. The parent rule
+// counts links in both directions since teams make them from either end.
+func defaultMetamodel() *Metamodel {
+	issues := []string{"capability", "feature", "requirement", "thread"}
+	return &Metamodel{
+		Links: []AllowedLink{
+			{From: "Sprint", To: issues},
+			{From: "Sprint", Links: []string{"sprint dependency"}, To: []string{"Sprint"}},
+			{From: "Sprint", Links: []string{"release"}, To: []string{"version"}},
+		},
+		Rules: []MetamodelRule{
+			{Name: "requirement-traces-to-feature", Type: "requirement", Link: "traces", To: []string{"feature"}, Min: 1, Severity: "warning"},
+			{Name: "feature-has-one-parent-capability", Type: "feature", Link: "parent", To: []string{"capability"}, Min: 1, Max: 1, Severity: "warning"},
+		},
+	}
+}
+
+// validate checks the graph against the structure rules, the metamodel and
+// the policy files, looks for dependency cycles, backwards and stale
+// dependencies, missed thread deadlines, orphans, over-committed sprints and
+// late forecasts and keeps the violations in the graph findings
+func (graph *Graph) validate(cfg *JiraConfig) []*Violation {
+	defer timeTrack(time.Now(), "Validate")
+
+	_, findings := graph.missingEnds()
+	model := cfg.Metamodel
+	if model == nil {
+		model = defaultMetamodel()
+	}
+	findings = append(findings, graph.checkLinks(model, cfg)...)
+	findings = append(findings, graph.checkRules(model, cfg)...)
+	findings = append(findings, graph.findCycles(cfg)...)
+	findings = append(findings, graph.checkSprintOrder(cfg)...)
+	findings = append(findings, graph.checkDeadlines(cfg)...)
+	findings = append(findings, graph.findOrphans(cfg)...)
+	findings = append(findings, graph.findStaleDependencies(cfg)...)
+	findings = append(findings, graph.checkCapacity()...)
+	findings = append(findings, graph.checkForecasts()...)
+	findings = append(findings, graph.checkPolicies(cfg)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Subject != findings[j].Subject {
+			return findings[i].Subject < findings[j].Subject
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	graph.Findings = findings
+	graph.logFindings()
+	return findings
+}
+
+// missingEnds counts the good edges and reports the edges with a missing end
+func (graph *Graph) missingEnds() (ok int, violations []*Violation) {
+	for _, item := range graph.Items {
+		if item.Group != "edges" {
+			continue
+		}
+		v := &Violation{Rule: "structure", Severity: "error", Subject: item.Data.Id}
+		if item.Data.Target == "" {
+			v.Message = "Empty Target"
+		} else if item.Data.Source == "" {
+			v.Message = "Empty Source"
+		} else if !graph.exists(item.Data.Target) {
+			v.Message = fmt.Sprintf("Mising Target Node: %s (%s)", item.Data.Target, item.Data.typeTarget)
+		} else if !graph.exists(item.Data.Source) {
+			v.Message = fmt.Sprintf("Mising Source Node: %s (%s)", item.Data.Source, item.Data.typeSource)
+		} else {
+			ok++
+			continue
+		}
+		violations = append(violations, v)
+	}
+	return ok, violations
+}
+
+// checkLinks reports the links that no allowed link entry of either end
+// permits
+func (graph *Graph) checkLinks(model *Metamodel, cfg *JiraConfig) (violations []*Violation) {
+	allowed := make(map[string][]AllowedLink)
+	for _, a := range model.Links {
+		allowed[a.From] = append(allowed[a.From], a)
+	}
+
+	for _, item := range graph.Items {
+		src, ok1 := graph.m[item.Data.Source]
+		tgt, ok2 := graph.m[item.Data.Target]
+		if item.Group != "edges" || !ok1 || !ok2 {
+			continue
+		}
+		for _, end := range [][2]*GraphItem{{src, tgt}, {tgt, src}} {
+			entries, constrained := allowed[end[0].Data.Type]
+			if !constrained {
+				continue
+			}
+			permitted := false
+			for _, a := range entries {
+				if containsType(a.To, end[1].Data.Type) && matchesLink(a.Links, item.Data.Type, cfg) {
+					permitted = true
+					break
+				}
+			}
+			if !permitted {
+				violations = append(violations, &Violation{
+					Rule:     "allowed-links",
+					Severity: "error",
+					Subject:  end[0].Data.Id,
+					Message:  fmt.Sprintf("%s may not have a %s link to %s %s", end[0].Data.Type, item.Data.Type, end[1].Data.Type, end[1].Data.Id),
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// checkRules counts the matching links of every node a rule applies to
+func (graph *Graph) checkRules(model *Metamodel, cfg *JiraConfig) (violations []*Violation) {
+	for _, rule := range model.Rules {
+		counts := make(map[string]int)
+		for _, item := range graph.Items {
+			src, ok1 := graph.m[item.Data.Source]
+			tgt, ok2 := graph.m[item.Data.Target]
+			if item.Group != "edges" || !ok1 || !ok2 || src == tgt || !matchesLink([]string{rule.Link}, item.Data.Type, cfg) {
+				continue
+			}
+			if rule.Direction != "in" && strings.EqualFold(src.Data.Type, rule.Type) && containsType(rule.To, tgt.Data.Type) {
+				counts[src.Data.Id]++
+			}
+			if rule.Direction != "out" && strings.EqualFold(tgt.Data.Type, rule.Type) && containsType(rule.To, src.Data.Type) {
+				counts[tgt.Data.Id]++
+			}
+		}
+
+		severity := rule.Severity
+		if severity == "" {
+			severity = "error"
+		}
+		what := strings.TrimSpace(rule.Link + " links to " + strings.Join(rule.To, " or "))
+		for _, item := range graph.Items {
+			if item.Group != "nodes" || !strings.EqualFold(item.Data.Type, rule.Type) {
+				continue
+			}
+			n := counts[item.Data.Id]
+			msg := ""
+			if n < rule.Min {
+				msg = fmt.Sprintf("has %d %s, needs at least %d", n, what, rule.Min)
+			} else if rule.Max > 0 && n > rule.Max {
+				msg = fmt.Sprintf("has %d %s, allows at most %d", n, what, rule.Max)
+			}
+			if msg != "" {
+				violations = append(violations, &Violation{Rule: rule.Name, Severity: severity, Subject: item.Data.Id, Message: msg})
+			}
+		}
+	}
+	return violations
+}
+
+// matchesLink checks a link type against link kinds or names, where no links
+// or an empty one match everything
+func matchesLink(links []string, linkType string, cfg *JiraConfig) bool {
+	if len(links) == 0 {
+		return true
+	}
+	kind := linkKind(linkType, cfg)
+	for _, l := range links {
+		if l == "" || strings.EqualFold(l, kind) || strings.EqualFold(l, linkType) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsType matches a node type against a list where an empty list
+// matches every type
+func containsType(types []string, nodeType string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if strings.EqualFold(t, nodeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// logFindings lists the violations grouped by issue and counted by rule
+func (graph *Graph) logFindings() {
+	byRule := make(map[string]int)
+	log.Printf("VALIDATION\n")
+	log.Printf("-----------------------------------------\n")
+	subject := ""
+	for _, v := range graph.Findings {
+		if v.Subject != subject {
+			subject = v.Subject
+			log.Printf("%s\n", subject)
+		}
+		log.Printf("\t%-7s %s: %s\n", v.Severity, v.Rule, v.Message)
+		byRule[v.Rule]++
+	}
+	log.Printf("-----------------------------------------\n")
+	for _, rule := range sortedRuleNames(byRule) {
+		log.Printf("%-33s:%6d\n", rule, byRule[rule])
+	}
+}
+
+func sortedRuleNames(m map[string]int) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
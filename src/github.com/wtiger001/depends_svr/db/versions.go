@@ -0,0 +1,192 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"net/http/httputil"
+	"sort"
+	"strings"
+	"time"
+
+	jira "github.com/andygrunwald/go-jira"
+)
+
+func loadVersions(cfg *JiraConfig, jiraClient *jira.Client, graph *Graph) (err error) {
+	defer timeTrack(time.Now(), "Load Versions")
+
+	for _, project := range cfg.Projects {
+		versions, err := requestVersions(project, cfg, jiraClient)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, version := range versions {
+			graph.versionNode(version.ID, version.Name, version.Description, version.StartDate, version.ReleaseDate, version.Released, version.Archived)
+		}
+	}
+
+	return nil
+}
+
+func requestVersions(projectKey string, cfg *JiraConfig, jiraClient *jira.Client) (versions []jira.Version, err error) {
+	defer timeTrack(time.Now(), "Request Versions")
+
+	url := fmt.Sprintf("rest/api/2/project/%s/versions", projectKey)
+	req, _ := jiraClient.NewRequest("GET", url, nil)
+
+	// Save a copy of this request for debugging.
+	if cfg.Debug {
+		requestDump, err := httputil.DumpRequest(req, true)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println(string(requestDump))
+	}
+
+	// Exectute the request
+	items := new([]jira.Version)
+	_, err = jiraClient.Do(req, items)
+	if err != nil {
+		return nil, err
+	}
+
+	return *items, nil
+}
+
+// versionNode finds or creates the node for a release. The release date is
+// kept as the finish date and the released / archived flags as the status.
+func (graph *Graph) versionNode(id string, name string, desc string, start string, release string, released *bool, archived *bool) *GraphItem {
+	real := versionID(id)
+	if !graph.exists(real) {
+		n := Node()
+		n.Data.Id = real
+		n.Data.Label = name
+		n.Data.Description = desc
+		n.Data.StartDate = start
+		n.Data.FinishDate = release
+		n.Data.Type = "version"
+		switch {
+		case archived != nil && *archived:
+			n.Data.Status = "archived"
+		case released != nil && *released:
+			n.Data.Status = "released"
+		default:
+			n.Data.Status = "unreleased"
+		}
+		graph.add(n)
+	}
+	return graph.m[real]
+}
+
+// versionLink connects an issue to a version it is fixed in
+func (graph *Graph) versionLink(version *jira.FixVersion, n *GraphItem) {
+	vNode := graph.versionNode(version.ID, version.Name, version.Description, version.StartDate, version.ReleaseDate, version.Released, version.Archived)
+
+	e := Edge()
+	e.Data.Id = n.Data.Id + "_VERSION_" + vNode.Data.Id
+	e.Data.Source = n.Data.Id
+	e.Data.Target = vNode.Data.Id
+	e.Data.Type = "fixed in"
+	graph.add(e)
+}
+
+func versionID(id string) string {
+	return validID("VERSION_" + id)
+}
+
+func fixVersionNames(versions []*jira.FixVersion) string {
+	names := make([]string, 0, len(versions))
+	for _, v := range versions {
+		names = append(names, v.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// versionsOf maps each issue to the versions it is fixed in
+func (graph *Graph) versionsOf() map[string][]string {
+	versions := make(map[string][]string)
+	for _, item := range graph.Items {
+		if item.Group == "edges" && item.Data.Type == "fixed in" {
+			versions[item.Data.Source] = appendUnique(versions[item.Data.Source], item.Data.Target)
+		}
+	}
+	return versions
+}
+
+// linkSprintVersions connects each sprint to the versions that the issues
+// planned in it are fixed in
+func (graph *Graph) linkSprintVersions() {
+	defer timeTrack(time.Now(), "Link Sprint Versions")
+
+	members, _ := graph.sprintMembership()
+	versionsOf := graph.versionsOf()
+
+	sprints := make([]string, 0, len(members))
+	for sprint := range members {
+		sprints = append(sprints, sprint)
+	}
+	sort.Strings(sprints)
+
+	cnt := 0
+	for _, sprint := range sprints {
+		counts := make(map[string]int)
+		var versions []string
+		for _, issue := range members[sprint] {
+			for _, version := range versionsOf[issue] {
+				counts[version]++
+				versions = appendUnique(versions, version)
+			}
+		}
+		sort.Strings(versions)
+		for _, version := range versions {
+			e := Edge()
+			e.Data.Id = sprint + "_CONTRIBUTES_" + version
+			e.Data.Source = sprint
+			e.Data.Target = version
+			e.Data.Type = "contributes to"
+			e.Data.Weight = counts[version]
+			graph.add(e)
+			cnt++
+		}
+	}
+	log.Printf("Linked %d sprints to versions with %d edges\n", len(sprints), cnt)
+}
+
+// checkVersionOrder warns about issue dependencies where the issue that is
+// depended on is released after the issue that depends on it
+func (graph *Graph) checkVersionOrder(cfg *JiraConfig) {
+	versionsOf := graph.versionsOf()
+	late := 0
+	for _, item := range graph.Items {
+		if item.Group != "edges" || !isDependencyLink(item.Data.Type, cfg) {
+			continue
+		}
+		provider, ok1 := graph.lastRelease(versionsOf[item.Data.Source])
+		dependent, ok2 := graph.firstRelease(versionsOf[item.Data.Target])
+		if ok1 && ok2 && provider.After(dependent) {
+			item.Data.Warning = "released after dependent version"
+			log.Printf("\tVersion conflict: %s is released %s after %s which depends on it is released %s\n",
+				item.Data.Source, provider.Format("2006-01-02"), item.Data.Target, dependent.Format("2006-01-02"))
+			late++
+		}
+	}
+	log.Printf("Found %d dependencies released after their dependent version\n", late)
+}
+
+func (graph *Graph) lastRelease(versions []string) (last time.Time, ok bool) {
+	for _, v := range versions {
+		if t, found := parseDate(graph.m[v].Data.FinishDate); found && (!ok || t.After(last)) {
+			last, ok = t, true
+		}
+	}
+	return last, ok
+}
+
+func (graph *Graph) firstRelease(versions []string) (first time.Time, ok bool) {
+	for _, v := range versions {
+		if t, found := parseDate(graph.m[v].Data.FinishDate); found && (!ok || t.Before(first)) {
+			first, ok = t, true
+		}
+	}
+	return first, ok
+}
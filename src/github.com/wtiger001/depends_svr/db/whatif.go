@@ -0,0 +1,307 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scenario is a set of hypothetical changes to try on a graph
+type Scenario struct {
+	Name    string           `json:"name"`
+	Changes []ScenarioChange `json:"changes"`
+}
+
+// ScenarioChange is one change to the plan:
+//
+//	move   plans the issue in the sprint instead of its current sprints
+//	slip   moves the start and finish of the sprint, or any dated node, by days
+//	unlink removes the link with the id, or the links between source and target
+//	link   adds a link of the type from source, the issue depended on, to target
+//
+// Sprints are named by id or by name.
+type ScenarioChange struct {
+	Action string `json:"action"`
+	Issue  string `json:"issue,omitempty"`
+	Sprint string `json:"sprint,omitempty"`
+	Days   int    `json:"days,omitempty"`
+	Link   string `json:"link,omitempty"`
+	Source string `json:"source,omitempty"`
+	Target string `json:"target,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// WhatIf is the difference a scenario makes: the findings it adds and
+// resolves and the changes to the derived status and forecasts
+type WhatIf struct {
+	Scenario string          `json:"scenario"`
+	Applied  []string        `json:"applied"`
+	New      []*Violation    `json:"new"`
+	Resolved []*Violation    `json:"resolved"`
+	Changes  []*WhatIfChange `json:"changes"`
+}
+
+// WhatIfChange is a derived field of a node that the scenario changes
+type WhatIfChange struct {
+	Subject string `json:"subject"`
+	Field   string `json:"field"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+}
+
+// LoadScenario reads a scenario file
+func LoadScenario(file string) (*Scenario, error) {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Scenario)
+	if err = json.Unmarshal(raw, s); err != nil {
+		return nil, err
+	}
+	if s.Name == "" {
+		s.Name = file
+	}
+	return s, nil
+}
+
+// WhatIfReport applies the scenario to a copy of the graph, runs the progress,
+// forecast and validation analyses on the copy and on the graph as it is and
+// reports the differences. The graph itself is left unchanged.
+func WhatIfReport(graph *Graph, scenario *Scenario, cfg *JiraConfig) (*WhatIf, error) {
+	defer timeTrack(time.Now(), "What If "+scenario.Name)
+
+	before, err := graph.clone()
+	if err != nil {
+		return nil, err
+	}
+	after, err := graph.clone()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &WhatIf{Scenario: scenario.Name}
+	for i, c := range scenario.Changes {
+		applied, err := after.apply(c, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("change %d (%s): %v", i+1, c.Action, err)
+		}
+		result.Applied = append(result.Applied, applied)
+	}
+
+	before.analyze(cfg)
+	after.analyze(cfg)
+
+	key := func(v *Violation) string { return v.Rule + "\x00" + v.Subject + "\x00" + v.Message }
+	had := make(map[string]bool)
+	for _, v := range before.Findings {
+		had[key(v)] = true
+	}
+	has := make(map[string]bool)
+	for _, v := range after.Findings {
+		has[key(v)] = true
+		if !had[key(v)] {
+			result.New = append(result.New, v)
+		}
+	}
+	for _, v := range before.Findings {
+		if !has[key(v)] {
+			result.Resolved = append(result.Resolved, v)
+		}
+	}
+
+	for _, item := range before.Items {
+		b := item.Data
+		a, ok := after.m[b.Id]
+		if item.Group != "nodes" || !ok || (b.Type != "thread" && b.Type != "capability") {
+			continue
+		}
+		fields := [][3]string{{"derived_status", b.DerivedStatus, a.Data.DerivedStatus}}
+		if b.Forecast != nil && a.Data.Forecast != nil {
+			fields = append(fields,
+				[3]string{"forecast_p85", b.Forecast.P85, a.Data.Forecast.P85},
+				[3]string{"forecast_on_time", fmt.Sprintf("%g%%", b.Forecast.OnTime), fmt.Sprintf("%g%%", a.Data.Forecast.OnTime)})
+		}
+		for _, f := range fields {
+			if f[1] != f[2] {
+				result.Changes = append(result.Changes, &WhatIfChange{Subject: b.Id, Field: f[0], Before: f[1], After: f[2]})
+			}
+		}
+	}
+	sort.SliceStable(result.Changes, func(i, j int) bool { return result.Changes[i].Subject < result.Changes[j].Subject })
+
+	log.Printf("Scenario %s: %d new findings, %d resolved, %d changes\n", scenario.Name, len(result.New), len(result.Resolved), len(result.Changes))
+	return result, nil
+}
+
+// Print writes the differences as text
+func (w *WhatIf) Print(out io.Writer) {
+	fmt.Fprintf(out, "What if: %s\n", w.Scenario)
+	for _, a := range w.Applied {
+		fmt.Fprintf(out, "  %s\n", a)
+	}
+	fmt.Fprintf(out, "\nNew findings (%d)\n", len(w.New))
+	for _, v := range w.New {
+		fmt.Fprintf(out, "  %-7s %s %s: %s\n", v.Severity, v.Subject, v.Rule, v.Message)
+	}
+	fmt.Fprintf(out, "\nResolved findings (%d)\n", len(w.Resolved))
+	for _, v := range w.Resolved {
+		fmt.Fprintf(out, "  %-7s %s %s: %s\n", v.Severity, v.Subject, v.Rule, v.Message)
+	}
+	fmt.Fprintf(out, "\nChanges (%d)\n", len(w.Changes))
+	for _, c := range w.Changes {
+		fmt.Fprintf(out, "  %s %s: %s -> %s\n", c.Subject, c.Field, c.Before, c.After)
+	}
+}
+
+// analyze derives the progress and forecasts and validates the graph
+func (graph *Graph) analyze(cfg *JiraConfig) {
+	graph.rollupProgress(cfg)
+	graph.forecast(cfg)
+	graph.validate(cfg)
+}
+
+// clone makes a deep copy of the graph through its JSON form
+func (graph *Graph) clone() (*Graph, error) {
+	raw, err := json.Marshal(graph)
+	if err != nil {
+		return nil, err
+	}
+	c := NewGraph()
+	if err = json.Unmarshal(raw, c); err != nil {
+		return nil, err
+	}
+	for _, item := range c.Items {
+		c.m[item.Data.Id] = item
+	}
+	for k, v := range graph.teams {
+		c.teams[k] = v
+	}
+	return c, nil
+}
+
+// apply makes one change to the graph and describes it
+func (graph *Graph) apply(c ScenarioChange, cfg *JiraConfig) (string, error) {
+	switch c.Action {
+	case "move":
+		issue, ok := graph.m[validID(c.Issue)]
+		if !ok || !graph.isIssue(issue.Data.Id) {
+			return "", fmt.Errorf("issue %s is not in the graph", c.Issue)
+		}
+		sprint := graph.findNode(c.Sprint, "Sprint")
+		if sprint == nil {
+			return "", fmt.Errorf("sprint %s is not in the graph", c.Sprint)
+		}
+
+		// The open points move with the issue
+		points := 0.0
+		if issueState(issue.Data, cfg) == "open" {
+			points = issue.Data.Points
+		}
+		edgeType := cfg.DependsLinkOut
+		_, sprintsOf := graph.sprintMembership()
+		var from []string
+		removed := make(map[string]bool)
+		for _, s := range sprintsOf[issue.Data.Id] {
+			e := graph.m[issue.Data.Id+"_SPRINT_"+s]
+			edgeType = e.Data.Type
+			removed[e.Data.Id] = true
+			if n := graph.m[s].Data; n.Status != "closed" {
+				n.Points -= points
+			}
+			from = append(from, graph.m[s].Data.Label)
+		}
+		graph.remove(removed)
+
+		e := Edge()
+		e.Data.Id = issue.Data.Id + "_SPRINT_" + sprint.Data.Id
+		e.Data.Source = sprint.Data.Id
+		e.Data.Target = issue.Data.Id
+		e.Data.Type = edgeType
+		e.Data.Description = "Issue " + issue.Data.Id
+		graph.add(e)
+		sprint.Data.Points += points
+		return fmt.Sprintf("Moved %s from %s to %s", issue.Data.Id, firstOf(strings.Join(from, ", "), "no sprint"), sprint.Data.Label), nil
+
+	case "slip":
+		n := graph.findNode(c.Sprint, "")
+		if n == nil {
+			return "", fmt.Errorf("%s is not in the graph", c.Sprint)
+		}
+		shifted := false
+		for _, date := range []*string{&n.Data.StartDate, &n.Data.FinishDate} {
+			if t, ok := parseDate(*date); ok {
+				*date = t.AddDate(0, 0, c.Days).String()
+				shifted = true
+			}
+		}
+		if !shifted {
+			return "", fmt.Errorf("%s has no dates", c.Sprint)
+		}
+		return fmt.Sprintf("Slipped %s by %d days", firstOf(n.Data.Label, n.Data.Id), c.Days), nil
+
+	case "unlink":
+		removed := make(map[string]bool)
+		source, target := validID(c.Source), validID(c.Target)
+		for _, item := range graph.Items {
+			if item.Group != "edges" {
+				continue
+			}
+			d := item.Data
+			ends := (d.Source == source && d.Target == target) || (d.Source == target && d.Target == source)
+			if (c.Link != "" && d.Id == validID(c.Link)) || (c.Link == "" && ends && (c.Type == "" || strings.EqualFold(c.Type, d.Type))) {
+				removed[d.Id] = true
+			}
+		}
+		if len(removed) == 0 {
+			return "", fmt.Errorf("no link %s matches", firstOf(c.Link, c.Source+" - "+c.Target))
+		}
+		graph.remove(removed)
+		return fmt.Sprintf("Removed %d links %s", len(removed), strings.Join(sortedSet(removed), ", ")), nil
+
+	case "link":
+		e := Edge()
+		e.Data.Source = validID(c.Source)
+		e.Data.Target = validID(c.Target)
+		e.Data.Type = firstOf(c.Type, cfg.DependsLinkOut)
+		if !graph.exists(e.Data.Source) || !graph.exists(e.Data.Target) {
+			return "", fmt.Errorf("%s or %s is not in the graph", c.Source, c.Target)
+		}
+		e.Data.Id = e.Data.Source + "_" + validID(e.Data.Type) + "_" + e.Data.Target
+		graph.add(e)
+		return fmt.Sprintf("Linked %s to %s (%s)", e.Data.Source, e.Data.Target, e.Data.Type), nil
+	}
+	return "", fmt.Errorf("unknown action %q", c.Action)
+}
+
+// findNode looks a node up by id or, failing that, by label, optionally only
+// among the nodes of a type
+func (graph *Graph) findNode(name string, nodeType string) *GraphItem {
+	if n, ok := graph.m[validID(name)]; ok && n.Group == "nodes" && (nodeType == "" || n.Data.Type == nodeType) {
+		return n
+	}
+	for _, item := range graph.Items {
+		if item.Group == "nodes" && (nodeType == "" || item.Data.Type == nodeType) && strings.EqualFold(item.Data.Label, name) {
+			return item
+		}
+	}
+	return nil
+}
+
+// remove takes the items with the given ids out of the graph
+func (graph *Graph) remove(ids map[string]bool) {
+	items := graph.Items[:0]
+	for _, item := range graph.Items {
+		if ids[item.Data.Id] {
+			delete(graph.m, item.Data.Id)
+			continue
+		}
+		items = append(items, item)
+	}
+	graph.Items = items
+}
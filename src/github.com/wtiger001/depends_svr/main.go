@@ -5,26 +5,50 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/howeyc/gopass"
 	"github.com/wtiger001/depends_svr/db"
 )
 
 func main() {
-	cfg := getConfig()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			runExport(os.Args[2:])
+			return
+		case "trace-matrix":
+			runTraceMatrix(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "forecast":
+			runForecast(os.Args[2:])
+			return
+		case "whatif":
+			runWhatIf(os.Args[2:])
+			return
+		}
+	}
+
+	cfg := getConfig(flag.CommandLine, os.Args[1:])
+	requireCredentials(cfg)
 
 	db.ExtractData(cfg)
 
 	fmt.Printf("Complete\n")
 }
 
-// Load the configuration from file, from the command line, etc.
-func getConfig() *db.JiraConfig {
-	var cfgFile string
+// Load the configuration from file, from the command line, etc. The flags of
+// a subcommand are parsed along with the common flags.
+func getConfig(flags *flag.FlagSet, args []string) *db.JiraConfig {
 	var cfg *db.JiraConfig
 
-	// Loads froma configuration file. Any other variables will override
-	flag.StringVar(&cfgFile, "cfg", "", "Configuration File")
+	// Loads froma configuration file. Any other variables will override, so
+	// the file is found before the other flags are registered with its values
+	cfgFile := cfgFlag(args)
+	flags.String("cfg", cfgFile, "Configuration File")
 	if cfgFile != "" && !exists(cfgFile) {
 		cfgFile = "config.json"
 	}
@@ -34,22 +58,43 @@ func getConfig() *db.JiraConfig {
 	if exists(cfgFile) {
 		err := cfg.Load(cfgFile)
 		if err != nil {
+			fmt.Printf("Failed to load %s: %v\n", cfgFile, err)
 			os.Exit(1)
 		}
 	}
 	cfg.ApplyDefaults()
 
 	// Load any command line overrides
-	flag.StringVar(&cfg.User, "user", cfg.User, "JIRA User Name")
-	flag.StringVar(&cfg.Password, "password", cfg.Password, "JIRA Password")
-	flag.StringVar(&cfg.JiraURL, "url", cfg.JiraURL, "JIRA URL")
-	flag.BoolVar(&cfg.Debug, "debug", cfg.Debug, "Enable Debuging mode")
-	flag.StringVar(&cfg.OutputFile, "out", cfg.OutputFile, "Output File")
-	flag.Parse()
-
-	// Validate and ask for missing fields from the command line
-	if !cfg.Valid() {
-		readFromTerminal(cfg)
+	flags.StringVar(&cfg.User, "user", cfg.User, "JIRA User Name")
+	flags.StringVar(&cfg.Password, "password", cfg.Password, "JIRA Password")
+	flags.StringVar(&cfg.JiraURL, "url", cfg.JiraURL, "JIRA URL")
+	flags.BoolVar(&cfg.Debug, "debug", cfg.Debug, "Enable Debuging mode")
+	flags.StringVar(&cfg.OutputFile, "out", cfg.OutputFile, "Output File")
+	flags.StringVar(&cfg.OutputFormat, "format", cfg.OutputFormat, "Output format (json, graphml, gexf, dot, mermaid, plantuml, sqlite, cypher, reqif, svg, ics, gantt, msproject), inferred from the output file extension by default")
+	flags.StringVar(&cfg.DotCluster, "cluster", cfg.DotCluster, "Group the DOT output into clusters by component or sprint")
+	flags.BoolVar(&cfg.ComponentLayer, "components", cfg.ComponentLayer, "Add component to component dependency edges")
+	flags.StringVar(&cfg.DSMFile, "dsm", cfg.DSMFile, "Component dependency structure matrix file (.csv or .json)")
+	flags.BoolVar(&cfg.SprintDependencies, "sprint-deps", cfg.SprintDependencies, "Add sprint to sprint dependency edges")
+	flags.StringVar(&cfg.KanbanBuckets, "kanban", cfg.KanbanBuckets, "Load kanban boards bucketed by fixversion, date or column")
+	flags.StringVar(&cfg.ProgramBoardFile, "program-board", cfg.ProgramBoardFile, "Program board HTML file (requires program-increments in the configuration)")
+	flags.StringVar(&cfg.HTMLFile, "html", cfg.HTMLFile, "Self-contained HTML report with the summary, validation results and an interactive viewer")
+	flags.StringVar(&cfg.Layout, "layout", cfg.Layout, "Precompute node positions with the layered or timeline layout")
+	flags.StringVar(&cfg.SVGFile, "svg", cfg.SVGFile, "SVG image of the graph, laid out with -layout (layered by default)")
+	flags.StringVar(&cfg.ForecastFile, "forecast", cfg.ForecastFile, "Delivery forecast report (HTML) with the P50/P85/P95 dates of the threads and capabilities")
+	imports := flags.String("import", strings.Join(cfg.Imports, ","), "Comma separated ReqIF or CSV files to merge into the graph")
+	policies := flags.String("policy", strings.Join(cfg.Policies, ","), "Comma separated Starlark policy files to check the graph with")
+	flags.Parse(args)
+	cfg.Imports = nil
+	for _, file := range strings.Split(*imports, ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			cfg.Imports = append(cfg.Imports, file)
+		}
+	}
+	cfg.Policies = nil
+	for _, file := range strings.Split(*policies, ",") {
+		if file = strings.TrimSpace(file); file != "" {
+			cfg.Policies = append(cfg.Policies, file)
+		}
 	}
 
 	if cfg.Debug {
@@ -59,6 +104,34 @@ func getConfig() *db.JiraConfig {
 	return cfg
 }
 
+// cfgFlag finds the value of the -cfg flag without parsing the other flags
+func cfgFlag(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == arg {
+			continue
+		}
+		if name == "cfg" && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, "cfg=") {
+			return strings.TrimPrefix(name, "cfg=")
+		}
+	}
+	return ""
+}
+
+// requireCredentials asks for any missing JIRA connection details
+func requireCredentials(cfg *db.JiraConfig) {
+	// Validate and ask for missing fields from the command line
+	if !cfg.Valid() {
+		readFromTerminal(cfg)
+	}
+}
+
 // Reads inputs from
 func readFromTerminal(cfg *db.JiraConfig) {
 	reader := bufio.NewReader(os.Stdin)